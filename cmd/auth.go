@@ -1,39 +1,152 @@
 package cmd
 
 import (
-	"encoding/json"
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/authstore"
 	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/dockercfg"
+	"github.com/hakantongur/harair/internal/shell"
 )
 
-type authMap map[string]struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+// credsCache remembers resolved credentials so each registry is looked up
+// (and reported in verbose mode) once per run. Its notes go to stderr so
+// that -o json/yaml output stays parseable.
+var credsCache = map[string][3]string{}
+
+// getCreds resolves credentials for a registry from, in order: --creds,
+// HARAIR_<NAME>_USERNAME/_PASSWORD, the harair auth store, docker's
+// config.json (including credential helpers) and finally config.yaml.
+// ok is false when no source has credentials for it.
+func getCreds(cfg *config.Config, name string) (string, string, bool) {
+	if c, ok := credsCache[name]; ok {
+		return c[0], c[1], c[2] != ""
+	}
+	user, pass, source := resolveCreds(cfg, name)
+	credsCache[name] = [3]string{user, pass, source}
+	if verbose {
+		if source == "" {
+			fmt.Fprintln(os.Stderr, color.CyanString("[harair] credentials for %s: none found", name))
+		} else {
+			fmt.Fprintln(os.Stderr, color.CyanString("[harair] credentials for %s: %s", name, source))
+		}
+	}
+	shell.RegisterCredentials(user, pass)
+	return user, pass, source != ""
 }
 
-func readAuthStore(p string) (authMap, error) {
-	var m authMap
-	if b, err := os.ReadFile(p); err == nil {
-		if len(b) == 0 {
-			return authMap{}, nil
+// flagCreds holds the --creds credentials by registry name, resolved by
+// parseCredsFlags before any command runs.
+var flagCreds map[string][2]string
+
+// parseCredsFlags resolves --creds <registry>=<user> entries. Passwords are
+// kept off argv: with --creds-stdin, stdin holds one per line in --creds
+// order; otherwise each comes from HARAIR_<REGISTRY>_PASSWORD.
+func parseCredsFlags(flags []string, fromStdin bool, stdin io.Reader) (map[string][2]string, error) {
+	if fromStdin && len(flags) == 0 {
+		return nil, errors.New("--creds-stdin needs --creds <registry>=<user>")
+	}
+	var lines *bufio.Scanner
+	if fromStdin {
+		lines = bufio.NewScanner(stdin)
+	}
+	out := map[string][2]string{}
+	for _, c := range flags {
+		name, user, ok := strings.Cut(c, "=")
+		if !ok || name == "" || user == "" {
+			return nil, fmt.Errorf("--creds %q: want <registry>=<user>", c)
+		}
+		env := "HARAIR_" + envName(name) + "_PASSWORD"
+		if strings.Contains(user, ":") {
+			return nil, fmt.Errorf("--creds %s: pass the username only; the password goes through --creds-stdin or %s", name, env)
 		}
-		if err := json.Unmarshal(b, &m); err != nil {
-			return nil, fmt.Errorf("parse auth store: %w", err)
+		var pass string
+		if lines != nil {
+			if !lines.Scan() {
+				if err := lines.Err(); err != nil {
+					return nil, fmt.Errorf("--creds-stdin: %w", err)
+				}
+				return nil, fmt.Errorf("--creds-stdin: no password line for %s", name)
+			}
+			pass = strings.TrimSuffix(lines.Text(), "\r")
+		} else if pass = os.Getenv(env); pass == "" {
+			return nil, fmt.Errorf("--creds %s: no password; pipe it in with --creds-stdin or set %s", name, env)
 		}
-		return m, nil
+		out[name] = [2]string{user, pass}
 	}
-	return authMap{}, nil
+	return out, nil
 }
 
-func getCreds(cfg *config.Config, name string) (string, string, bool) {
+func resolveCreds(cfg *config.Config, name string) (user, pass, source string) {
+	// 1) explicit --creds name=user (see parseCredsFlags)
+	if c, ok := flagCreds[name]; ok {
+		return c[0], c[1], "--creds flag"
+	}
+
+	// 2) environment
+	prefix := "HARAIR_" + envName(name)
+	if u, p := os.Getenv(prefix+"_USERNAME"), os.Getenv(prefix+"_PASSWORD"); u != "" || p != "" {
+		return u, p, "environment (" + prefix + "_*)"
+	}
+
+	// 3) harair auth store (written by `login`)
+	if p, err := authStorePath(cfg); err == nil {
+		store, encrypted, err := authstore.Load(p, authSecret(cfg, false))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, color.YellowString("ignoring auth store: %v", err))
+		} else if e, ok := store[name]; ok {
+			if !encrypted {
+				fmt.Fprintln(os.Stderr, color.YellowString("auth store %s is not encrypted; run `harair auth encrypt`", p))
+			}
+			return e.Username, e.Password, "auth store (" + p + ")"
+		}
+	}
+
 	r, ok := cfg.Registries[name]
 	if !ok {
-		return "", "", false
+		return "", "", ""
+	}
+
+	// 4) docker config.json, keyed by registry host
+	if host := trimScheme(registryURL(r)); host != "" {
+		u, p, found, err := dockercfg.Lookup(host)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, color.YellowString("ignoring docker config for %s: %v", host, err))
+		} else if found {
+			return u, p, "docker config (" + dockercfg.Path() + ")"
+		}
+	}
+
+	// 5) config.yaml
+	if r.Username != "" || r.Password != "" {
+		return r.Username, r.Password, "config.yaml"
+	}
+	return "", "", ""
+}
+
+var envNameRe = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// envName turns a registry name into an env var fragment ("harbor-1" -> "HARBOR_1").
+func envName(name string) string {
+	return envNameRe.ReplaceAllString(strings.ToUpper(name), "_")
+}
+
+// authStorePath is where `login` keeps credentials: auth_store from
+// config.yaml (relative paths are under $HOME), or ~/.harair/auth.json.
+func authStorePath(cfg *config.Config) (string, error) {
+	p := cfg.AuthStore
+	if p == "" {
+		p = filepath.Join(".harair", "auth.json")
 	}
-	return r.Username, r.Password, true
+	return authStorePathHomeFallback(p)
 }
 
 func authStorePathHomeFallback(p string) (string, error) {
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/authstore"
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var rotateNewKeyFile string
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage the encrypted auth store",
+	Long: `Manage the encrypted auth store written by login.
+
+The store key is derived from a key file (auth_key_file in config.yaml or
+HARAIR_KEY_FILE) or a passphrase (HARAIR_PASSPHRASE, or prompted).`,
+}
+
+var authEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt a plaintext auth store in place",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+		path, err := authStorePath(cfg)
+		if err != nil {
+			return err
+		}
+		store, encrypted, err := authstore.Load(path, authSecret(cfg, false))
+		if err != nil {
+			return err
+		}
+		if encrypted {
+			color.Yellow("%s is already encrypted (use `harair auth rotate-key` to change the key)", path)
+			return nil
+		}
+		secret, err := authSecret(cfg, true)()
+		if err != nil {
+			return err
+		}
+		if err := authstore.Save(path, store, secret); err != nil {
+			return err
+		}
+		color.Green("Encrypted %d entries in %s", len(store), path)
+		return nil
+	},
+}
+
+var authRotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Re-encrypt the auth store with a new passphrase or key file",
+	Long: `Re-encrypt the auth store with a new passphrase or key file.
+
+The current key comes from the usual sources; the new one from --new-key-file,
+HARAIR_NEW_PASSPHRASE, or a prompt. Remember to point auth_key_file /
+HARAIR_KEY_FILE / HARAIR_PASSPHRASE at the new key afterwards.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+		path, err := authStorePath(cfg)
+		if err != nil {
+			return err
+		}
+		store, _, err := authstore.Load(path, authSecret(cfg, false))
+		if err != nil {
+			return err
+		}
+		secret, err := readSecret(rotateNewKeyFile, "", "HARAIR_NEW_PASSPHRASE", "New auth store passphrase", true)
+		if err != nil {
+			return err
+		}
+		if err := authstore.Save(path, store, secret); err != nil {
+			return err
+		}
+		color.Green("Re-encrypted %s with the new key", path)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authEncryptCmd)
+	authCmd.AddCommand(authRotateKeyCmd)
+	authRotateKeyCmd.Flags().StringVar(&rotateNewKeyFile, "new-key-file", "", "Key file for the new key (else HARAIR_NEW_PASSPHRASE or prompt)")
+}
+
+// authStoreSecret caches the store secret so it is prompted for at most once.
+var authStoreSecret []byte
+
+// authSecret returns the SecretFunc for the auth store: auth_key_file or
+// HARAIR_KEY_FILE, then HARAIR_PASSPHRASE, then a terminal prompt (entered
+// twice when confirm is set, i.e. when a new key is being chosen).
+func authSecret(cfg *config.Config, confirm bool) authstore.SecretFunc {
+	return func() ([]byte, error) {
+		if authStoreSecret != nil {
+			return authStoreSecret, nil
+		}
+		secret, err := readSecret(cfg.AuthKeyFile, "HARAIR_KEY_FILE", "HARAIR_PASSPHRASE", "Auth store passphrase", confirm)
+		if err != nil {
+			return nil, err
+		}
+		authStoreSecret = secret
+		return secret, nil
+	}
+}
+
+func readSecret(keyFile, keyFileEnv, passEnv, prompt string, confirm bool) ([]byte, error) {
+	if keyFile == "" && keyFileEnv != "" {
+		keyFile = os.Getenv(keyFileEnv)
+	}
+	if keyFile != "" {
+		p, err := authStorePathHomeFallback(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("read key file: %w", err)
+		}
+		b = bytes.TrimSpace(b)
+		if len(b) < 16 {
+			return nil, fmt.Errorf("key file %s: need at least 16 bytes of key material", p)
+		}
+		return b, nil
+	}
+
+	if v := os.Getenv(passEnv); v != "" {
+		return []byte(v), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, authstore.ErrNoSecret
+	}
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	first, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if len(first) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirm {
+		fmt.Fprintf(os.Stderr, "%s (again): ", prompt)
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(first, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return first, nil
+}
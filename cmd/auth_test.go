@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/executor"
+)
+
+func TestParseCredsFlags(t *testing.T) {
+	t.Setenv("HARAIR_HARBOR_1_PASSWORD", "from-env")
+
+	for _, tc := range []struct {
+		name  string
+		flags []string
+		stdin string // "" => no --creds-stdin
+		want  map[string][2]string
+		err   string
+	}{
+		{"none", nil, "", map[string][2]string{}, ""},
+		{"env", []string{"harbor-1=robot"}, "", map[string][2]string{"harbor-1": {"robot", "from-env"}}, ""},
+		{"stdin in order", []string{"a=u1", "b=u2"}, "p1\r\np2\n",
+			map[string][2]string{"a": {"u1", "p1"}, "b": {"u2", "p2"}}, ""},
+		{"stdin short", []string{"a=u1", "b=u2"}, "p1\n", nil, "no password line for b"},
+		{"no password", []string{"other=robot"}, "", nil, "HARAIR_OTHER_PASSWORD"},
+		{"password on argv", []string{"harbor-1=robot:s3cret"}, "", nil, "username only"},
+		{"no user", []string{"harbor-1"}, "", nil, "want <registry>=<user>"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseCredsFlags(tc.flags, tc.stdin != "", strings.NewReader(tc.stdin))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("err = %v, want %q", err, tc.err)
+				}
+				if strings.Contains(err.Error(), "s3cret") {
+					t.Errorf("error echoes the password: %v", err)
+				}
+				return
+			}
+			if err != nil || len(got) != len(tc.want) {
+				t.Fatalf("got %v %v, want %v", got, err, tc.want)
+			}
+			for k, v := range tc.want {
+				if got[k] != v {
+					t.Errorf("%s: got %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+
+	if _, err := parseCredsFlags(nil, true, strings.NewReader("x\n")); err == nil {
+		t.Error("--creds-stdin without --creds: no error")
+	}
+}
+
+func TestDirectEndpoint(t *testing.T) {
+	cfg := &config.Config{Registries: map[string]config.Registry{
+		"h1": {URL: "https://harbor1.local", Username: "u1", Password: "p1"},
+		"h2": {URL: "https://harbor2-api.local", RegistryURL: "harbor2.local:5000", Username: "u2", Password: "p2"},
+	}}
+	testConfig(t, nil) // keep the lookups away from the user's home
+	for ref, want := range map[string]executor.Endpoint{
+		"docker://harbor1.local/p/app:v1":      {User: "u1", Pass: "p1", Insecure: true},
+		"docker://HARBOR2.local:5000/p/app:v1": {User: "u2", Pass: "p2", Insecure: true},
+		"docker://harbor2-api.local/p/app:v1":  {Insecure: true}, // the API host serves no images
+		"docker://docker.io/library/alpine:3":  {Insecure: true},
+		"oci:/tmp/layout:app":                  {Insecure: true},
+	} {
+		if got := directEndpoint(cfg, ref, true); got != want {
+			t.Errorf("%s: %+v, want %+v", ref, got, want)
+		}
+	}
+}
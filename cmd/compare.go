@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/hakantongur/harair/internal/harbor"
+)
+
+// taskState says how a planned tag relates to what the destination holds.
+type taskState int
+
+const (
+	stateNew      taskState = iota // tag not on the destination
+	stateUpToDate                  // same digest already under that tag
+	stateChanged                   // tag exists with a different digest
+)
+
+func (s taskState) String() string {
+	switch s {
+	case stateUpToDate:
+		return "up-to-date"
+	case stateChanged:
+		return "changed"
+	default:
+		return "new"
+	}
+}
+
+// destTag is what the destination holds under a tag.
+type destTag struct {
+	Digest   string
+	Children []string // child manifests of an index, sorted
+}
+
+// destIndex caches the tag -> destTag map of destination repos.
+type destIndex struct {
+	hc    *harbor.Client
+	repos map[string]map[string]destTag
+}
+
+func newDestIndex(hc *harbor.Client) *destIndex {
+	return &destIndex{hc: hc, repos: map[string]map[string]destTag{}}
+}
+
+// tags returns the tag -> destTag map of project/repo. A repo or project
+// that doesn't exist yet is empty; any other listing error is returned, so
+// an unreachable destination isn't mistaken for an empty one.
+func (d *destIndex) tags(project, repo string) (map[string]destTag, error) {
+	key := project + "/" + repo
+	if m, ok := d.repos[key]; ok {
+		return m, nil
+	}
+	arts, err := d.hc.ListArtifacts(project, repo)
+	if err != nil && !harbor.IsNotFound(err) {
+		return nil, fmt.Errorf("list %s/%s: %w", project, repo, err)
+	}
+	m := map[string]destTag{}
+	for _, a := range arts {
+		for _, t := range a.Tags {
+			m[t.Name] = destTag{Digest: a.Digest, Children: childDigests(a.References)}
+		}
+	}
+	d.repos[key] = m
+	return m, nil
+}
+
+// classify compares the destination tag with digest. children, when not nil,
+// are the child manifests a platform-filtered copy of an index writes: the
+// filtered index has a digest of its own, so the children are compared.
+func (d *destIndex) classify(project, repo, tag, digest string, children []string) (taskState, error) {
+	tags, err := d.tags(project, repo)
+	if err != nil {
+		return stateNew, err
+	}
+	have, ok := tags[tag]
+	switch {
+	case !ok:
+		return stateNew, nil
+	case have.matches(digest, children):
+		return stateUpToDate, nil
+	default:
+		return stateChanged, nil
+	}
+}
+
+// matches reports whether the tag holds digest, or exactly children when set.
+func (t destTag) matches(digest string, children []string) bool {
+	if children == nil {
+		return t.Digest == digest
+	}
+	return slices.Equal(t.Children, children)
+}
+
+// childDigests returns the sorted digests of an index's children.
+func childDigests(refs []harbor.Reference) []string {
+	var out []string
+	for _, r := range refs {
+		out = append(out, r.ChildDigest)
+	}
+	sort.Strings(out)
+	return out
+}
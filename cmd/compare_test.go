@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	dst := newFakeHarbor(t)
+	dst.add("p", "a/app", imageArt("sha256:one", "v1"))
+	dst.add("p", "a/app", imageArt("sha256:old", "v2"))
+	filtered := indexArt("sha256:filtered", []string{"linux/amd64"}, "v3")
+	filtered.References[0].ChildDigest = "sha256:src-amd64"
+	dst.add("p", "a/app", filtered)
+	idx := newDestIndex(dst.client())
+
+	for _, tc := range []struct {
+		project, repo, tag, digest string
+		children                   []string
+		want                       taskState
+	}{
+		{"p", "a/app", "v1", "sha256:one", nil, stateUpToDate},
+		{"p", "a/app", "v2", "sha256:new", nil, stateChanged},
+		{"p", "a/app", "v9", "sha256:one", nil, stateNew},
+		{"p", "a/app", "v3", "sha256:src", []string{"sha256:src-amd64"}, stateUpToDate},
+		{"p", "a/app", "v3", "sha256:src", []string{"sha256:src-amd64", "sha256:src-arm64"}, stateChanged},
+		{"p", "a/app", "v3", "sha256:src", nil, stateChanged},
+		{"p", "missing", "v1", "sha256:one", nil, stateNew},
+		{"nope", "a/app", "v1", "sha256:one", nil, stateNew},
+	} {
+		got, err := idx.classify(tc.project, tc.repo, tc.tag, tc.digest, tc.children)
+		if err != nil || got != tc.want {
+			t.Errorf("%s/%s:%s %s %v: got %s %v, want %s", tc.project, tc.repo, tc.tag, tc.digest, tc.children, got, err, tc.want)
+		}
+	}
+}
+
+func TestClassifyListError(t *testing.T) {
+	dst := newFakeHarbor(t)
+	dst.Status = http.StatusUnauthorized
+	if _, err := newDestIndex(dst.client()).classify("p", "app", "v1", "sha256:one", nil); err == nil {
+		t.Error("an unauthorized destination classified as empty")
+	}
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/harbor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffProject   string
+	diffRepo      string
+	diffTags      []string
+	diffOutput    string
+	diffTemplate  string
+	diffAll       bool
+	diffPlatforms []string
+)
+
+// Drift kinds reported by diff.
+const (
+	driftSourceOnly = "source-only"
+	driftDestOnly   = "destination-only"
+	driftChanged    = "changed"
+	driftInSync     = "in-sync"
+)
+
+// diffEntry is one tag compared between two registries.
+type diffEntry struct {
+	Status     string `json:"status" yaml:"status"`
+	Repo       string `json:"repo" yaml:"repo"`
+	Tag        string `json:"tag" yaml:"tag"`
+	Source     string `json:"source_digest,omitempty" yaml:"source_digest,omitempty"`
+	Dest       string `json:"destination_digest,omitempty" yaml:"destination_digest,omitempty"`
+	SourceSize int64  `json:"source_size,omitempty" yaml:"source_size,omitempty"`
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [from-registry] [to-registry]",
+	Short: "Compare a project's tags between two registries",
+	Long: `Compare a project's tags between two registries through the Harbor API.
+
+Lists tags only on the source, tags only on the destination, and tags whose
+digests differ (--all also lists tags that are in sync). Tags copied with
+sync --platforms hold an index of their own; pass the same --platforms and
+they are compared by their child manifests instead.
+
+Exit codes: 0 when the destination matches the source, 4 on drift, 1 on error
+(including a registry that can't be listed; only missing repos count as empty).`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fromReg, toReg := args[0], args[1]
+		if diffProject == "" {
+			return fmt.Errorf("please provide --project")
+		}
+		if err := checkOutput(diffOutput, diffTemplate); err != nil {
+			return err
+		}
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+		src, err := newRegistrySource(cfg, fromReg)
+		if err != nil {
+			return err
+		}
+		dst, err := newRegistrySource(cfg, toReg)
+		if err != nil {
+			return err
+		}
+
+		// repos on either side
+		repos := map[string]bool{}
+		if diffRepo != "" {
+			repos[diffRepo] = true
+		} else {
+			srcRepos, err := src.HC.ListRepos(diffProject)
+			if err != nil {
+				return fmt.Errorf("%s: %w", fromReg, err)
+			}
+			for _, r := range srcRepos {
+				repos[repoName(r.Name)] = true
+			}
+			// a missing destination project simply means everything is source-only
+			dstRepos, err := dst.HC.ListRepos(diffProject)
+			if err != nil && !harbor.IsNotFound(err) {
+				return fmt.Errorf("%s: %w", toReg, err)
+			}
+			for _, r := range dstRepos {
+				repos[repoName(r.Name)] = true
+			}
+		}
+		names := make([]string, 0, len(repos))
+		for r := range repos {
+			names = append(names, r)
+		}
+		sort.Strings(names)
+
+		platforms, err := copyPlatforms(nil, diffPlatforms)
+		if err != nil {
+			return err
+		}
+		dstIdx := newDestIndex(dst.HC)
+		var entries []diffEntry
+		counts := map[string]int{}
+		for _, repo := range names {
+			// a repo missing on one side is empty there; --repo must exist on the source
+			arts, err := src.HC.ListArtifacts(diffProject, repo)
+			if err != nil && (diffRepo != "" || !harbor.IsNotFound(err)) {
+				return fmt.Errorf("%s: %w", fromReg, err)
+			}
+			srcTags := map[string]planArtifact{}
+			for _, a := range arts {
+				for _, t := range a.Tags {
+					srcTags[t.Name] = planArtifact{Digest: a.Digest, Size: a.Size, Children: a.References}
+				}
+			}
+			dstTags, err := dstIdx.tags(diffProject, repo)
+			if err != nil {
+				return fmt.Errorf("%s: %w", toReg, err)
+			}
+
+			tags := map[string]bool{}
+			for t := range srcTags {
+				tags[t] = true
+			}
+			for t := range dstTags {
+				tags[t] = true
+			}
+			sorted := make([]string, 0, len(tags))
+			for t := range tags {
+				if globAny(diffTags, t) {
+					sorted = append(sorted, t)
+				}
+			}
+			sort.Strings(sorted)
+
+			for _, t := range sorted {
+				sa, dt := srcTags[t], dstTags[t]
+				e := diffEntry{Repo: repo, Tag: t, Source: sa.Digest, Dest: dt.Digest, SourceSize: sa.Size}
+				switch {
+				case e.Dest == "":
+					e.Status = driftSourceOnly
+				case e.Source == "":
+					e.Status = driftDestOnly
+				case !dt.matches(sa.Digest, platformChildren(sa, platforms)):
+					e.Status = driftChanged
+				default:
+					e.Status = driftInSync
+				}
+				counts[e.Status]++
+				if e.Status != driftInSync || diffAll {
+					entries = append(entries, e)
+				}
+			}
+		}
+
+		table := isTable(diffOutput, diffTemplate)
+		if table {
+			color.Cyan("%s/%s -> %s/%s:", fromReg, diffProject, toReg, diffProject)
+		}
+		err = printItems(diffOutput, diffTemplate, entries, func(w io.Writer) {
+			fmt.Fprintln(w, "STATUS\tREPO\tTAG\tSOURCE\tDESTINATION")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Status, e.Repo, e.Tag,
+					orDash(shortDigest(e.Source)), orDash(shortDigest(e.Dest)))
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if table {
+			fmt.Printf("Summary: %d source-only, %d destination-only, %d changed, %d in sync\n",
+				counts[driftSourceOnly], counts[driftDestOnly], counts[driftChanged], counts[driftInSync])
+		}
+
+		if drift := counts[driftSourceOnly] + counts[driftDestOnly] + counts[driftChanged]; drift > 0 {
+			cmd.SilenceUsage = true
+			return &exitCodeError{code: exitDrift, err: fmt.Errorf("%d tag(s) differ", drift)}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVar(&diffProject, "project", "", "Project to compare")
+	diffCmd.Flags().StringVar(&diffRepo, "repo", "", "Only compare this repo")
+	diffCmd.Flags().StringSliceVar(&diffTags, "tags", nil, "Tag globs to compare (default: all)")
+	diffCmd.Flags().BoolVar(&diffAll, "all", false, "Also list tags that are in sync")
+	diffCmd.Flags().StringSliceVar(&diffPlatforms, "platforms", nil, "Platforms the tags were copied with (as sync --platforms), so platform subsets compare in sync")
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", outputTable, "Output format: table, wide, json or yaml")
+	diffCmd.Flags().StringVar(&diffTemplate, "template", "", "Go template applied to each entry, e.g. '{{.Status}} {{.Repo}}:{{.Tag}}'")
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestDiffExitCodes(t *testing.T) {
+	src, dst := newFakeHarbor(t), newFakeHarbor(t)
+	src.add("p", "app", imageArt("sha256:a", "v1"))
+	src.add("p", "app", imageArt("sha256:b", "v2"))
+	dst.add("p", "app", imageArt("sha256:a", "v1"))
+	dst.add("p", "app", imageArt("sha256:b", "v2"))
+	cfg := testConfig(t, map[string]*fakeHarbor{"src": src, "dst": dst})
+	diff := func(args ...string) (int, []diffEntry) {
+		t.Helper()
+		code, out := runCmd(t, cfg, append([]string{"diff", "src", "dst", "--project", "p", "-o", "json"}, args...)...)
+		var entries []diffEntry
+		if code != exitError {
+			if err := json.Unmarshal([]byte(out), &entries); err != nil {
+				t.Fatalf("exit %d: %v\n%s", code, err, out)
+			}
+		}
+		return code, entries
+	}
+
+	if code, entries := diff(); code != exitOK || len(entries) != 0 {
+		t.Errorf("in sync: exit %d, %+v", code, entries)
+	}
+	if code, entries := diff("--all"); code != exitOK || len(entries) != 2 || entries[0].Status != driftInSync {
+		t.Errorf("in sync --all: exit %d, %+v", code, entries)
+	}
+
+	src.add("p", "app", imageArt("sha256:c", "v3"))   // source only
+	dst.add("p", "app", imageArt("sha256:old", "v0")) // destination only
+	dst.add("p", "tools", imageArt("sha256:t", "v1")) // repo only on the destination
+	code, entries := diff()
+	got := map[string]string{}
+	for _, e := range entries {
+		got[e.Repo+":"+e.Tag] = e.Status
+	}
+	if code != exitDrift || len(got) != 3 || got["app:v3"] != driftSourceOnly || got["app:v0"] != driftDestOnly || got["tools:v1"] != driftDestOnly {
+		t.Errorf("drift: exit %d, %+v", code, entries)
+	}
+
+	// a repo missing on one side is empty (tools above), but a failing
+	// listing is an error, as is a --repo the source doesn't have
+	if code, _ := diff("--repo", "missing"); code != exitError {
+		t.Errorf("--repo missing on the source: exit %d, want %d", code, exitError)
+	}
+	dst.Fail["projects/p/repositories/app"] = http.StatusInternalServerError
+	if code, _ := diff(); code != exitError {
+		t.Errorf("destination listing fails: exit %d, want %d", code, exitError)
+	}
+	delete(dst.Fail, "projects/p/repositories/app")
+	src.Status = http.StatusUnauthorized
+	if code, _ := diff(); code != exitError {
+		t.Errorf("source down: exit %d, want %d", code, exitError)
+	}
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/bundle"
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/executor"
+	"github.com/hakantongur/harair/internal/harbor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportProject       string
+	exportRepo          string
+	exportTags          []string
+	exportRulesPath     string
+	exportOutput        string
+	exportDockerNetwork string
+	exportDryRun        bool
+	exportPlatforms     []string
+)
+
+// bundleMount is where the bundle directory is mounted inside the skopeo container.
+const bundleMount = "/bundle"
+
+var exportCmd = &cobra.Command{
+	Use:   "export [from-registry]",
+	Short: "Write images to an offline OCI bundle (directory or tarball)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fromReg := args[0]
+
+		if exportProject == "" {
+			color.Red("Please provide --project")
+			return nil
+		}
+		if exportOutput == "" {
+			return fmt.Errorf("please pass --output <dir> or --output <file.tar[.gz]>")
+		}
+
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+		fr, ok := cfg.Registries[fromReg]
+		if !ok {
+			return fmt.Errorf("registry %q not in %s", fromReg, cfgPath)
+		}
+		fu, fp, _ := getCreds(cfg, fromReg)
+
+		srcAPI := apiURL(fr)
+		if strings.TrimSpace(srcAPI) == "" {
+			return fmt.Errorf("registry %q: api_url/url is empty in config.yaml", fromReg)
+		}
+		srcHC := harbor.New(srcAPI, fu, fp, fr.Insecure)
+
+		plan, err := buildPlan(srcHC, exportProject, exportRepo, exportTags, exportRulesPath)
+		if err != nil {
+			return err
+		}
+		arts := resolvePlan(srcHC, plan)
+		if len(arts) == 0 {
+			color.Yellow("Nothing to export for project %q.", exportProject)
+			return nil
+		}
+
+		srcReg := trimScheme(registryURL(fr))
+		platforms := make([][]string, len(arts))
+		for i, a := range arts {
+			if platforms[i], err = copyPlatforms(a.Platforms, exportPlatforms); err != nil {
+				return err
+			}
+			if err := checkPlatformEngine(cfg, platforms[i]); err != nil {
+				return err
+			}
+		}
+		if exportDryRun {
+			for i, a := range arts {
+				color.Yellow("[dry-run] export docker://%s/%s/%s:%s (%s)%s", srcReg, a.Project, a.Repo, a.Tag, a.Digest, describePlatforms(a, platforms[i]))
+			}
+			return nil
+		}
+
+		// Tarballs are assembled in a scratch directory first.
+		workDir := exportOutput
+		if bundle.IsArchive(exportOutput) {
+			workDir, err = os.MkdirTemp("", "harair-export-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(workDir)
+		}
+		workDir, err = filepath.Abs(workDir)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(workDir, 0o755); err != nil {
+			return err
+		}
+		layout := filepath.Join(workDir, bundle.LayoutDir)
+
+		var volumes []string
+		layoutRef := layout
+		if executor.IsContainer(cfg.SkopeoPath) {
+			volumes = []string{workDir + ":" + bundleMount}
+			layoutRef = bundleMount + "/" + bundle.LayoutDir
+		}
+
+		var tasks []copyTask
+		for i, a := range arts {
+			tasks = append(tasks, copyTask{
+				srcRef:    pinRef(fmt.Sprintf("docker://%s/%s/%s:%s", srcReg, a.Project, a.Repo, a.Tag), a.Digest),
+				dstRef:    fmt.Sprintf("oci:%s:%s", layoutRef, bundle.LayoutRef(a.Project, a.Repo, a.Tag)),
+				platforms: platforms[i],
+			})
+		}
+
+		exec, err := newExecutor(cfg, exportDockerNetwork, volumes)
+		if err != nil {
+			return err
+		}
+		defer exec.Close()
+
+		// skopeo does not lock index.json, so writes into one layout are serialized.
+		results := runCopies(tasks, exec, 1, executor.Endpoint{User: fu, Pass: fp, Insecure: fr.Insecure}, executor.Endpoint{}, nil)
+		if err := summarizeCopies(results); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+
+		index, err := bundle.ReadIndex(layout)
+		if err != nil {
+			return fmt.Errorf("read OCI layout: %w", err)
+		}
+
+		// An artifact skipped as missing on the source leaves a hole in the
+		// bundle, so it fails the export like any other copy.
+		var missing int
+		for i, a := range arts {
+			if _, ok := index[bundle.LayoutRef(a.Project, a.Repo, a.Tag)]; !ok {
+				color.Red("not exported: %s", tasks[i].srcRef)
+				missing++
+			}
+		}
+		if missing > 0 {
+			cmd.SilenceUsage = true
+			code := exitPartialFailure
+			if missing == len(arts) {
+				code = exitTotalFailure
+			}
+			return &exitCodeError{code: code, err: fmt.Errorf("%d of %d artifact(s) not exported", missing, len(arts))}
+		}
+
+		m := &bundle.Manifest{Version: 1, Created: time.Now().UTC(), Source: fromReg}
+		for i, a := range arts {
+			ref := bundle.LayoutRef(a.Project, a.Repo, a.Tag)
+			d := index[ref]
+			size, err := bundle.Verify(layout, d)
+			if err != nil {
+				return fmt.Errorf("verify %s: %w", ref, err)
+			}
+			m.Artifacts = append(m.Artifacts, bundle.Artifact{
+				SourceRef:    fmt.Sprintf("docker://%s/%s/%s:%s", srcReg, a.Project, a.Repo, a.Tag),
+				Project:      a.Project,
+				Repo:         a.Repo,
+				Tag:          a.Tag,
+				Ref:          ref,
+				SourceDigest: a.Digest,
+				Digest:       d.Digest,
+				MediaType:    d.MediaType,
+				Size:         size,
+				Platforms:    platforms[i],
+			})
+		}
+		if err := bundle.WriteManifest(workDir, m); err != nil {
+			return err
+		}
+
+		if bundle.IsArchive(exportOutput) {
+			if err := bundle.Pack(workDir, exportOutput); err != nil {
+				return fmt.Errorf("write %s: %w", exportOutput, err)
+			}
+		}
+		color.Green("Exported %d artifact(s) to %s", len(m.Artifacts), exportOutput)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportProject, "project", "", "Project to export")
+	exportCmd.Flags().StringVar(&exportRepo, "repo", "", "Specific repo to export (optional)")
+	exportCmd.Flags().StringSliceVar(&exportTags, "tags", nil, "Tag globs to include (default: all)")
+	exportCmd.Flags().StringVar(&exportRulesPath, "rules", "", "Path to rules.yaml (overrides --repo/--tags)")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Bundle directory, or tarball path ending in .tar, .tar.gz or .tgz")
+	exportCmd.Flags().StringVar(&exportDockerNetwork, "docker-network", "", "Docker network for skopeo")
+	exportCmd.Flags().StringSliceVar(&exportPlatforms, "platforms", nil, "Platforms of multi-arch images to export: all (default) or os/arch[/variant] list; rules may set their own. Subsets need skopeo_path: native")
+	exportCmd.Flags().BoolVar(&exportDryRun, "dry-run", false, "Print what would be exported, do not execute")
+}
@@ -0,0 +1,351 @@
+package cmd
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/harbor"
+)
+
+// fakeHarbor serves the project, repository, artifact, quota and search
+// calls of the Harbor API from memory, and records the changes made to it.
+type fakeHarbor struct {
+	*httptest.Server
+	Status      int            // when set, every request fails with it
+	Fail        map[string]int // path (projects/p, or v2 for the registry API) -> status for it and below
+	QuotaStatus int            // when set, quota calls fail with it (no admin rights)
+	Drop        []string       // project metadata keys ignored on create
+
+	mu       sync.Mutex
+	projects map[string]map[string][]harbor.Artifact // project -> repo -> artifacts
+	meta     map[string]*harbor.Project
+	quotas   map[string]harbor.Quota // by project name
+	calls    []string                // "METHOD path" of every change
+}
+
+func newFakeHarbor(t *testing.T) *fakeHarbor {
+	t.Helper()
+	h := &fakeHarbor{projects: map[string]map[string][]harbor.Artifact{}, Fail: map[string]int{},
+		meta: map[string]*harbor.Project{}, quotas: map[string]harbor.Quota{}}
+	h.Server = httptest.NewServer(http.HandlerFunc(h.serve))
+	t.Cleanup(h.Close)
+	return h
+}
+
+// add puts artifact a into project/repo.
+func (h *fakeHarbor) add(project, repo string, a harbor.Artifact) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.projects[project] == nil {
+		h.projects[project] = map[string][]harbor.Artifact{}
+	}
+	h.projects[project][repo] = append(h.projects[project][repo], a)
+}
+
+// project sets the metadata and storage quota (used, hard) of project name.
+func (h *fakeHarbor) project(name string, metadata map[string]string, used, hard int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.setProject(name, metadata, &hard)
+	q := h.quotas[name]
+	q.Used = map[string]int64{"storage": used}
+	h.quotas[name] = q
+}
+
+// setProject creates or replaces project name; a nil hard keeps the quota.
+func (h *fakeHarbor) setProject(name string, metadata map[string]string, hard *int64) {
+	p, ok := h.meta[name]
+	if !ok {
+		p = &harbor.Project{ProjectID: len(h.meta) + 1, Name: name}
+		h.meta[name] = p
+	}
+	p.Metadata = metadata
+	if hard != nil {
+		q := h.quotas[name]
+		q.Ref.ID, q.Ref.Name = p.ProjectID, name
+		q.Hard = map[string]int64{"storage": *hard}
+		h.quotas[name] = q
+	}
+}
+
+// getProject returns project name, which exists once it has metadata or
+// repositories.
+func (h *fakeHarbor) getProject(name string) (harbor.Project, bool) {
+	if p, ok := h.meta[name]; ok {
+		return *p, true
+	}
+	if _, ok := h.projects[name]; ok {
+		return harbor.Project{Name: name}, true
+	}
+	return harbor.Project{}, false
+}
+
+// Calls returns the changes made so far, as "METHOD path".
+func (h *fakeHarbor) Calls() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.calls...)
+}
+
+// tags returns the tags of the artifacts in project/repo, by digest.
+func (h *fakeHarbor) tags(project, repo string) map[string][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := map[string][]string{}
+	for _, a := range h.projects[project][repo] {
+		out[a.Digest] = a.TagNames()
+	}
+	return out
+}
+
+// imageArt returns an image artifact with tags.
+func imageArt(digest string, tags ...string) harbor.Artifact {
+	a := harbor.Artifact{Digest: digest, Size: 10, Type: "IMAGE"}
+	for _, t := range tags {
+		a.Tags = append(a.Tags, harbor.Tag{Name: t})
+	}
+	return a
+}
+
+// indexArt returns a multi-arch image artifact with a child per platform.
+func indexArt(digest string, platforms []string, tags ...string) harbor.Artifact {
+	a := imageArt(digest, tags...)
+	for _, p := range platforms {
+		goos, arch, _ := strings.Cut(p, "/")
+		a.References = append(a.References, harbor.Reference{ChildDigest: digest + "-" + arch,
+			Platform: &harbor.Platform{OS: goos, Architecture: arch}})
+	}
+	return a
+}
+
+// client returns a Harbor API client for h.
+func (h *fakeHarbor) client() *harbor.Client {
+	return harbor.New(h.URL, "admin", "pw", true)
+}
+
+func (h *fakeHarbor) serve(w http.ResponseWriter, r *http.Request) {
+	if h.Status != 0 {
+		http.Error(w, http.StatusText(h.Status), h.Status)
+		return
+	}
+	if r.URL.Query().Get("page") != "" && r.URL.Query().Get("page") != "1" {
+		writeJSON(w, []any{}) // everything fits on the first page
+		return
+	}
+	// repo names come single- or double-encoded
+	var parts []string
+	for _, p := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(r.URL.EscapedPath(), "/api/v2.0"), "/"), "/") {
+		for strings.Contains(p, "%") {
+			u, err := url.PathUnescape(p)
+			if err != nil {
+				break
+			}
+			p = u
+		}
+		parts = append(parts, p)
+	}
+	path := strings.Join(parts, "/")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for prefix, status := range h.Fail {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+	}
+	if r.Method != http.MethodGet {
+		h.calls = append(h.calls, r.Method+" "+path)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && path == "users/current":
+		if u, p, _ := r.BasicAuth(); u != "admin" || p != "pw" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, harbor.User{UserID: 1, Username: "admin"})
+	case r.Method == http.MethodGet && path == "projects":
+		out := []harbor.Project{}
+		for _, name := range h.projectNames() {
+			p, _ := h.getProject(name)
+			p.RepoCount = len(h.projects[name])
+			out = append(out, p)
+		}
+		writeJSON(w, out)
+	case r.Method == http.MethodPost && path == "projects":
+		var req harbor.ProjectReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := h.getProject(req.Name); ok {
+			http.Error(w, "project already exists", http.StatusConflict)
+			return
+		}
+		md := map[string]string{}
+		for k, v := range req.Metadata {
+			if !slices.Contains(h.Drop, k) {
+				md[k] = v
+			}
+		}
+		h.setProject(req.Name, md, req.StorageLimit)
+		w.WriteHeader(http.StatusCreated)
+	case len(parts) == 2 && parts[0] == "projects":
+		p, ok := h.getProject(parts[1])
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, p)
+		case http.MethodPut:
+			var req harbor.ProjectReq
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			md := maps.Clone(p.Metadata)
+			if md == nil {
+				md = map[string]string{}
+			}
+			maps.Copy(md, req.Metadata)
+			h.setProject(p.Name, md, nil)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	case r.Method == http.MethodGet && path == "quotas":
+		if h.QuotaStatus != 0 {
+			http.Error(w, http.StatusText(h.QuotaStatus), h.QuotaStatus)
+			return
+		}
+		out := []harbor.Quota{}
+		for _, name := range h.projectNames() {
+			q, ok := h.quotas[name]
+			if id := r.URL.Query().Get("reference_id"); ok && (id == "" || id == strconv.Itoa(q.Ref.ID)) {
+				out = append(out, q)
+			}
+		}
+		writeJSON(w, out)
+	case r.Method == http.MethodGet && path == "search":
+		q := r.URL.Query().Get("q")
+		res := harbor.SearchResult{Projects: []harbor.Project{}, Repositories: []harbor.SearchRepository{}}
+		for _, name := range h.projectNames() {
+			p, _ := h.getProject(name)
+			if strings.Contains(name, q) {
+				res.Projects = append(res.Projects, p)
+			}
+			for _, repo := range slices.Sorted(maps.Keys(h.projects[name])) {
+				if full := name + "/" + repo; strings.Contains(full, q) {
+					res.Repositories = append(res.Repositories, harbor.SearchRepository{ProjectName: name,
+						ProjectPublic: p.Public(), RepositoryName: full, ArtifactCount: len(h.projects[name][repo])})
+				}
+			}
+		}
+		writeJSON(w, res)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[0] == "projects" && parts[2] == "repositories":
+		repos, ok := h.projects[parts[1]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		out := []harbor.Repository{}
+		for _, name := range slices.Sorted(maps.Keys(repos)) {
+			out = append(out, harbor.Repository{Name: parts[1] + "/" + name, ArtifactCount: len(repos[name])})
+		}
+		writeJSON(w, out)
+	case r.Method == http.MethodGet && len(parts) == 5 && parts[0] == "projects" && parts[2] == "repositories" && parts[4] == "artifacts":
+		arts, ok := h.projects[parts[1]][parts[3]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, arts)
+	case r.Method == http.MethodDelete && len(parts) >= 6 && parts[0] == "projects" && parts[2] == "repositories" && parts[4] == "artifacts":
+		arts := h.projects[parts[1]][parts[3]]
+		i := slices.IndexFunc(arts, func(a harbor.Artifact) bool { return a.Digest == parts[5] || slices.Contains(a.TagNames(), parts[5]) })
+		if i < 0 {
+			http.NotFound(w, r)
+			return
+		}
+		switch {
+		case len(parts) == 6:
+			h.projects[parts[1]][parts[3]] = slices.Delete(arts, i, i+1)
+		case len(parts) == 8 && parts[6] == "tags":
+			n := len(arts[i].Tags)
+			arts[i].Tags = slices.DeleteFunc(slices.Clone(arts[i].Tags), func(t harbor.Tag) bool { return t.Name == parts[7] })
+			if len(arts[i].Tags) == n {
+				http.NotFound(w, r)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// projectNames returns the names of every project, sorted.
+func (h *fakeHarbor) projectNames() []string {
+	names := slices.Collect(maps.Keys(h.projects))
+	for name := range h.meta {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// testConfig returns a config with the fake Harbors as registries, and keeps
+// credential lookups away from the user's home directory.
+func testConfig(t *testing.T, regs map[string]*fakeHarbor) *config.Config {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("DOCKER_CONFIG", filepath.Join(home, ".docker"))
+	cfg := &config.Config{Registries: map[string]config.Registry{}, AuthStore: filepath.Join(home, "auth.json")}
+	for name, h := range regs {
+		cfg.Registries[name] = config.Registry{APIURL: h.URL, RegistryURL: strings.TrimPrefix(h.URL, "http://"),
+			Insecure: true, Username: "admin", Password: "pw"}
+	}
+	credsCache = map[string][3]string{}
+	t.Cleanup(func() { credsCache = map[string][3]string{} })
+	return cfg
+}
+
+// fastRetries makes runCopies retry n times without waiting.
+func fastRetries(t *testing.T, n int) {
+	t.Helper()
+	oldRetries, oldBackoff := retries, retryBackoff
+	retries, retryBackoff = n, time.Millisecond
+	t.Cleanup(func() { retries, retryBackoff = oldRetries, oldBackoff })
+}
+
+// writeFile writes content to a file in a temp dir and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/executor"
+	"github.com/hakantongur/harair/internal/harbor"
+	"github.com/hakantongur/harair/internal/shell"
+)
+
+// pullChart fetches an OCI chart from the source registry as a classic .tgz
+// into dir and returns its path.
+func pullChart(cfg *config.Config, src *registrySource, a planArtifact, dir string) (string, error) {
+	helm := cfg.HelmPath
+	if helm == "" {
+		helm = "helm"
+	}
+	reg := registryURL(src.Reg)
+	// pull the digest seen at planning time, as image copies do
+	ref := fmt.Sprintf("oci://%s/%s/%s", trimScheme(reg), a.Project, a.Repo)
+	args := []string{"pull", ref, "--version", a.Tag}
+	if a.Digest != "" {
+		args = []string{"pull", ref + "@" + a.Digest}
+	}
+	args = append(args, "--destination", dir)
+	if src.Reg.Insecure {
+		if strings.HasPrefix(reg, "https://") {
+			args = append(args, "--insecure-skip-tls-verify")
+		} else {
+			args = append(args, "--plain-http")
+		}
+	}
+	// helm reads docker-style auth from --registry-config; keep creds off argv
+	auth, err := executor.NewAuthFiles()
+	if err != nil {
+		return "", err
+	}
+	defer auth.Close()
+	regConfig, err := auth.Write(trimScheme(reg), src.User, src.Pass)
+	if err != nil {
+		return "", err
+	}
+	if regConfig != "" {
+		args = append(args, "--registry-config", regConfig)
+	}
+	if _, err := shell.Run(helm, args...); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", chartName(a.Repo), a.Tag)), nil
+}
+
+// errNoChartRepo is the upload error on a destination without the classic
+// chart repository API.
+var errNoChartRepo = errors.New("destination has no chart repository API (removed in Harbor 2.8)")
+
+// checkChartRepo reports whether the destination still serves the classic
+// chart repository API, warning when it doesn't.
+func checkChartRepo(dstHC *harbor.Client, toReg string) (bool, error) {
+	ok, err := dstHC.HasChartRepo()
+	if err != nil {
+		return false, fmt.Errorf("%s: chart repository: %w", toReg, err)
+	}
+	if !ok {
+		color.Yellow("%s has no chart repository API (Harbor 2.8+); skipping --chartrepo uploads, charts are copied as OCI only", toReg)
+	}
+	return ok, nil
+}
+
+// uploadClassicCharts pulls each chart as .tgz and pushes it to the
+// destination project's chart repository.
+func uploadClassicCharts(cfg *config.Config, src *registrySource, dstHC *harbor.Client, charts []planArtifact) []copyResult {
+	if len(charts) == 0 {
+		return nil
+	}
+	var results []copyResult
+	fail := func(t copyTask, err error, d time.Duration) {
+		results = append(results, copyResult{task: t, outcome: copyFailed, err: err, attempts: 1, duration: d})
+	}
+
+	// resumed and retried tasks were planned against a destination that may
+	// have been upgraded since
+	if ok, err := dstHC.HasChartRepo(); err != nil || !ok {
+		if err == nil {
+			err = errNoChartRepo
+		}
+		color.Red("chartrepo upload skipped: %v", err)
+		for _, a := range charts {
+			fail(chartUploadTask(src, dstHC, a), err, 0)
+		}
+		return results
+	}
+
+	dir, err := os.MkdirTemp("", "harair-charts-")
+	if err != nil {
+		color.Red("chartrepo upload skipped: %v", err)
+		for _, a := range charts {
+			fail(chartUploadTask(src, dstHC, a), err, 0)
+		}
+		return results
+	}
+	defer os.RemoveAll(dir)
+
+	for _, a := range charts {
+		t := chartUploadTask(src, dstHC, a)
+		start := time.Now()
+		tgz, err := pullChart(cfg, src, a, dir)
+		if err != nil {
+			color.Red("helm pull failed: %v", err)
+			fail(t, err, time.Since(start))
+			continue
+		}
+		if err := dstHC.UploadChart(a.Project, tgz); err != nil {
+			color.Red("chartrepo upload failed: %v", err)
+			fail(t, err, time.Since(start))
+			continue
+		}
+		results = append(results, copyResult{task: t, outcome: copySucceeded, attempts: 1, duration: time.Since(start)})
+		color.Green("chartrepo: %s/%s %s", a.Project, chartName(a.Repo), a.Tag)
+	}
+	return results
+}
+
+// chartUploadTask describes a classic chart upload in copy results.
+func chartUploadTask(src *registrySource, dstHC *harbor.Client, a planArtifact) copyTask {
+	return copyTask{
+		srcRef: fmt.Sprintf("oci://%s/%s/%s:%s", trimScheme(registryURL(src.Reg)), a.Project, a.Repo, a.Tag),
+		dstRef: fmt.Sprintf("%s/api/chartrepo/%s/charts/%s-%s.tgz", dstHC.Base, a.Project, chartName(a.Repo), a.Tag),
+	}
+}
+
+// chartName is the last path segment of a chart repo ("charts/x" -> "x").
+func chartName(repo string) string {
+	return repo[strings.LastIndex(repo, "/")+1:]
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hakantongur/harair/internal/config"
+)
+
+// fakeHelm writes a helm stand-in that logs its argv and exits with code.
+func fakeHelm(t *testing.T, code string) (path, log string) {
+	t.Helper()
+	dir := t.TempDir()
+	path, log = filepath.Join(dir, "helm"), filepath.Join(dir, "argv")
+	script := "#!/bin/sh\necho \"$@\" >" + log + "\nexit " + code + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path, log
+}
+
+func TestPullChartAuthFile(t *testing.T) {
+	src := &registrySource{Name: "src", Reg: config.Registry{RegistryURL: "harbor.local"}, User: "u", Pass: "chart-Secret"}
+	a := planArtifact{Project: "charts", Repo: "app", Tag: "1.2.0", Digest: "sha256:abc", Chart: true}
+
+	for _, code := range []string{"0", "1"} {
+		helm, log := fakeHelm(t, code)
+		_, err := pullChart(&config.Config{HelmPath: helm}, src, a, t.TempDir())
+		if (err != nil) != (code != "0") {
+			t.Fatalf("exit %s: err = %v", code, err)
+		}
+		b, _ := os.ReadFile(log)
+		argv := strings.Fields(string(b))
+		if strings.Contains(string(b), "chart-Secret") {
+			t.Errorf("exit %s: password on argv: %v", code, argv)
+		}
+		if len(argv) < 2 || argv[1] != "oci://harbor.local/charts/app@sha256:abc" || slices.Contains(argv, "--version") {
+			t.Errorf("exit %s: want a pull by digest, got %v", code, argv)
+		}
+		var regConfig string
+		for i, arg := range argv {
+			if arg == "--registry-config" && i+1 < len(argv) {
+				regConfig = argv[i+1]
+			}
+		}
+		if regConfig == "" {
+			t.Fatalf("exit %s: no --registry-config in %v", code, argv)
+		}
+		if _, err := os.Stat(regConfig); !os.IsNotExist(err) {
+			t.Errorf("exit %s: %s left behind", code, regConfig)
+		}
+	}
+}
+
+func TestChartRepoMissing(t *testing.T) {
+	dst := newFakeHarbor(t) // no /api/chartrepo, as on Harbor 2.8+
+	if ok, err := checkChartRepo(dst.client(), "dst"); ok || err != nil {
+		t.Errorf("checkChartRepo = %v %v, want false", ok, err)
+	}
+
+	helm, log := fakeHelm(t, "0")
+	src := &registrySource{Name: "src", Reg: config.Registry{RegistryURL: "harbor.local"}}
+	charts := []planArtifact{{Project: "charts", Repo: "app", Tag: "1.2.0", Digest: "sha256:abc", Chart: true}}
+	results := uploadClassicCharts(&config.Config{HelmPath: helm}, src, dst.client(), charts)
+	if len(results) != 1 || results[0].outcome != copyFailed || !errors.Is(results[0].err, errNoChartRepo) {
+		t.Errorf("results %+v, want a failure naming the missing API", results)
+	}
+	if _, err := os.Stat(log); !os.IsNotExist(err) {
+		t.Error("chart pulled for a destination that can't take it")
+	}
+}
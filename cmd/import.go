@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/bundle"
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/executor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importProject       string
+	importDryRun        bool
+	importDockerNetwork string
+	importConcurrency   int
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [bundle] [to-registry]",
+	Short: "Push an offline bundle into a registry (defaults to --dry-run)",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, toReg := args[0], args[1]
+
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+		tr, ok := cfg.Registries[toReg]
+		if !ok {
+			return fmt.Errorf("registry %q not in %s", toReg, cfgPath)
+		}
+		tu, tp, _ := getCreds(cfg, toReg)
+
+		dir := src
+		if bundle.IsArchive(src) {
+			dir, err = os.MkdirTemp("", "harair-import-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			if err := bundle.Unpack(src, dir); err != nil {
+				return fmt.Errorf("unpack %s: %w", src, err)
+			}
+		}
+		dir, err = filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+
+		m, err := bundle.ReadManifest(dir)
+		if err != nil {
+			return err
+		}
+		layout := filepath.Join(dir, bundle.LayoutDir)
+		index, err := bundle.ReadIndex(layout)
+		if err != nil {
+			return fmt.Errorf("read OCI layout: %w", err)
+		}
+
+		// Check every blob before pushing anything.
+		var bad int
+		for _, a := range m.Artifacts {
+			if err := verifyBundleArtifact(layout, index, a); err != nil {
+				color.Red("corrupt: %s: %v", a.Ref, err)
+				bad++
+			}
+		}
+		if bad > 0 {
+			return fmt.Errorf("%d of %d artifact(s) in %s failed verification", bad, len(m.Artifacts), src)
+		}
+		color.Green("Verified %d artifact(s) from %s (exported from %s at %s)",
+			len(m.Artifacts), src, m.Source, m.Created.Format("2006-01-02 15:04:05"))
+
+		layoutRef := layout
+		var volumes []string
+		if executor.IsContainer(cfg.SkopeoPath) {
+			volumes = []string{dir + ":" + bundleMount + ":ro"}
+			layoutRef = bundleMount + "/" + bundle.LayoutDir
+		}
+
+		dstReg := trimScheme(registryURL(tr))
+		var tasks []copyTask
+		for _, a := range m.Artifacts {
+			project := a.Project
+			if importProject != "" {
+				project = importProject
+			}
+			srcRef := fmt.Sprintf("oci:%s:%s", layoutRef, a.Ref)
+			dstRef := fmt.Sprintf("docker://%s/%s/%s:%s", dstReg, project, a.Repo, a.Tag)
+
+			if importDryRun {
+				color.Yellow("[dry-run] skopeo copy %s -> %s", srcRef, dstRef)
+				continue
+			}
+			tasks = append(tasks, copyTask{srcRef: srcRef, dstRef: dstRef})
+		}
+		if importDryRun {
+			return nil
+		}
+
+		exec, err := newExecutor(cfg, importDockerNetwork, volumes)
+		if err != nil {
+			return err
+		}
+		defer exec.Close()
+
+		results := runCopies(tasks, exec, importConcurrency, executor.Endpoint{}, executor.Endpoint{User: tu, Pass: tp, Insecure: tr.Insecure}, nil)
+
+		for i, r := range results {
+			status := color.GreenString("OK  ")
+			switch r.outcome {
+			case copySkipped:
+				status = color.YellowString("SKIP")
+			case copyFailed:
+				status = color.RedString("FAIL")
+			}
+			fmt.Printf("%s  %s  %s\n", status, r.task.dstRef, m.Artifacts[i].Digest)
+		}
+		if err := summarizeCopies(results); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		color.Green("Imported %d artifact(s) into %s", len(tasks), toReg)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&importProject, "project", "", "Destination project (default: the project recorded in the bundle)")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", true, "Print what would be pushed, do not execute")
+	importCmd.Flags().StringVar(&importDockerNetwork, "docker-network", "", "Docker network for skopeo")
+	importCmd.Flags().IntVar(&importConcurrency, "concurrency", 2, "Number of parallel copy operations")
+}
+
+// verifyBundleArtifact checks that the layout still holds the manifest the
+// bundle recorded for a, and that every blob it references is intact.
+func verifyBundleArtifact(layout string, index map[string]bundle.Descriptor, a bundle.Artifact) error {
+	d, ok := index[a.Ref]
+	if !ok {
+		return fmt.Errorf("not in OCI index")
+	}
+	if d.Digest != a.Digest {
+		return fmt.Errorf("index digest %s, bundle recorded %s", d.Digest, a.Digest)
+	}
+	size, err := bundle.Verify(layout, d)
+	if err != nil {
+		return err
+	}
+	if size != a.Size {
+		return fmt.Errorf("size %d, bundle recorded %d", size, a.Size)
+	}
+	return nil
+}
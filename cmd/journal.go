@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/executor"
+	"github.com/hakantongur/harair/internal/harbor"
+	"github.com/hakantongur/harair/internal/journal"
+	"github.com/hakantongur/harair/internal/registry"
+	"github.com/hakantongur/harair/internal/report"
+)
+
+// defaultJournalPath is where sync writes its journal without --journal.
+func defaultJournalPath() (string, error) {
+	name := "sync-" + time.Now().UTC().Format("20060102-150405") + ".jsonl"
+	return authStorePathHomeFallback(filepath.Join(".harair", "journal", name))
+}
+
+// journalState maps a copy outcome to the journal task state.
+func journalState(o copyOutcome) journal.State {
+	switch o {
+	case copySkipped:
+		return journal.Skipped
+	case copyFailed:
+		return journal.Failed
+	default:
+		return journal.Done
+	}
+}
+
+// pinRef returns ref with its tag replaced by digest (repo@sha256:...), so
+// the copy reads exactly what was planned even if the tag has moved since.
+// The destination keeps the tag.
+func pinRef(ref, digest string) string {
+	if digest == "" {
+		return ref
+	}
+	r, err := registry.ParseRef(ref)
+	if err != nil || r.Transport != "docker" {
+		return ref
+	}
+	r.Reference = digest
+	return r.String()
+}
+
+// runJournal executes the unfinished tasks of j, recording each outcome in
+// the journal (and rep) as it completes.
+func runJournal(j *journal.Journal, cfg *config.Config, exec executor.Executor, workers int,
+	dstHC *harbor.Client, dst executor.Endpoint, rep *taskReport) []copyResult {
+
+	return runTasks(j.Unfinished(), cfg, exec, workers, dstHC, dst, func(t journal.Task, r copyResult) {
+		if err := j.Set(t.ID, journalState(r.outcome), string(r.errClass()), r.err); err != nil {
+			color.Red("journal: %v", err)
+		}
+		rep.executed(t, r)
+	})
+}
+
+// runTasks executes tasks, one worker pool per source registry, calling
+// record with each outcome as it completes. Copies read the source digest
+// recorded at planning time; tasks are planned pinned, and older journals
+// and failed-task files that hold tag refs are pinned here.
+func runTasks(todo []journal.Task, cfg *config.Config, exec executor.Executor, workers int,
+	dstHC *harbor.Client, dst executor.Endpoint, record func(journal.Task, copyResult)) []copyResult {
+
+	// group by source registry, in plan order
+	var order []string
+	bySource := map[string][]journal.Task{}
+	for _, t := range todo {
+		if _, ok := bySource[t.From]; !ok {
+			order = append(order, t.From)
+		}
+		bySource[t.From] = append(bySource[t.From], t)
+	}
+
+	var results []copyResult
+	for _, from := range order {
+		var copies, uploads []journal.Task
+		for _, t := range bySource[from] {
+			if t.Kind == journal.KindChartRepo {
+				uploads = append(uploads, t)
+			} else {
+				copies = append(copies, t)
+			}
+		}
+
+		src, err := newRegistrySource(cfg, from)
+		if err != nil {
+			for _, t := range bySource[from] {
+				r := copyResult{task: copyTask{srcRef: t.SrcRef, dstRef: t.DstRef}, outcome: copyFailed, err: err}
+				record(t, r)
+				results = append(results, r)
+			}
+			color.Red("source %s: %v", from, err)
+			continue
+		}
+
+		tasks := make([]copyTask, len(copies))
+		for i, t := range copies {
+			tasks[i] = copyTask{srcRef: pinRef(t.SrcRef, t.Digest), dstRef: t.DstRef, platforms: t.Platforms}
+		}
+		results = append(results, runCopies(tasks, exec, workers,
+			executor.Endpoint{User: src.User, Pass: src.Pass, Insecure: src.Reg.Insecure}, dst,
+			func(i int, r copyResult) { record(copies[i], r) })...)
+
+		if len(uploads) > 0 {
+			charts := make([]planArtifact, len(uploads))
+			for i, t := range uploads {
+				charts[i] = planArtifact{Project: t.Project, Repo: t.Repo, Tag: t.Tag, Digest: t.Digest, Chart: true}
+			}
+			for i, r := range uploadClassicCharts(cfg, src, dstHC, charts) {
+				record(uploads[i], r)
+				results = append(results, r)
+			}
+		}
+	}
+	return results
+}
+
+// resumeSync continues the unfinished tasks of the journal at path.
+func resumeSync(path string, rep *taskReport) error {
+	started := time.Now().UTC()
+	j, err := journal.Open(path)
+	if err != nil {
+		return fmt.Errorf("open journal: %w", err)
+	}
+	defer j.Close()
+
+	todo := j.Unfinished()
+	color.Cyan("Resuming %s: %d of %d task(s) unfinished (destination %s)", path, len(todo), len(j.Tasks), j.Header.To)
+	if dryRun {
+		for _, t := range todo {
+			state, _, _ := j.State(t.ID)
+			if t.Kind == journal.KindChartRepo {
+				color.Yellow("[dry-run] (%s) chartrepo upload %s -> %s", state, t.SrcRef, t.DstRef)
+				continue
+			}
+			color.Yellow("[dry-run] (%s) skopeo copy %s -> %s%s", state, pinRef(t.SrcRef, t.Digest), t.DstRef, platformNote(t.Platforms))
+		}
+		return nil
+	}
+	if len(todo) == 0 {
+		color.Green("Nothing to resume.")
+		return nil
+	}
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+	tr, ok := cfg.Registries[j.Header.To]
+	if !ok {
+		return fmt.Errorf("registry %q not in %s", j.Header.To, cfgPath)
+	}
+	tu, tp, _ := getCreds(cfg, j.Header.To)
+	dstHC := harbor.New(apiURL(tr), tu, tp, tr.Insecure)
+
+	exec, err := newExecutor(cfg, syncDockerNetwork, nil)
+	if err != nil {
+		return err
+	}
+	defer exec.Close()
+
+	results := runJournal(j, cfg, exec, maxConcurrent, dstHC,
+		executor.Endpoint{User: tu, Pass: tp, Insecure: tr.Insecure}, rep)
+	err = summarizeJournal(j, results)
+	if verr := verifySync(j, cfg, rep); err == nil {
+		err = verr
+	}
+	rep.write(report.Report{Command: "sync --resume", To: j.Header.To, Started: started})
+	return err
+}
+
+// summarizeJournal prints the copy summary, writes the failed-task file
+// (--failed-file, else next to the journal) and says how to continue.
+func summarizeJournal(j *journal.Journal, results []copyResult) error {
+	err := summarizeCopies(results)
+
+	var failed []journal.FailedTask
+	for _, t := range j.Tasks {
+		state, class, msg := j.State(t.ID)
+		if state != journal.Failed {
+			continue
+		}
+		if t.Kind == journal.KindCopy {
+			t.SrcRef = pinRef(t.SrcRef, t.Digest)
+		}
+		failed = append(failed, journal.FailedTask{Task: t, To: j.Header.To, Class: class, Error: msg})
+	}
+	if len(failed) > 0 {
+		path := syncFailedFile
+		if path == "" {
+			path = strings.TrimSuffix(j.Path, filepath.Ext(j.Path)) + ".failed.json"
+		}
+		if werr := journal.WriteFailed(path, failed); werr != nil {
+			color.Red("write failed-task file: %v", werr)
+		} else {
+			color.Yellow("%d failed task(s) written to %s; retry with: harair retry %s --dry-run=false", len(failed), path, path)
+		}
+	}
+	if n := len(j.Unfinished()); n > 0 {
+		color.Yellow("%d task(s) unfinished; resume with: harair sync --resume %s --dry-run=false", n, j.Path)
+	}
+	return err
+}
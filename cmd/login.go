@@ -1,21 +1,37 @@
 package cmd
 
 import (
-	"encoding/json"
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/authstore"
 	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/harbor"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	loginUsername      string
+	loginPasswordStdin bool
+	loginSkipVerify    bool
 )
 
 var loginCmd = &cobra.Command{
 	Use:   "login [registry]",
-	Short: "Store credentials for a registry (writes to auth store file)",
-	Args:  cobra.ExactArgs(1),
+	Short: "Check credentials against Harbor and store them in the auth store",
+	Long: `Check credentials against Harbor and store them in the auth store.
+
+Without flags, login prompts for a username and a hidden password. For scripts,
+pass --username and pipe the password in with --password-stdin:
+
+  echo "$HARBOR_PASS" | harair login harbor1 --username robot --password-stdin`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		regName := args[0]
 
@@ -28,20 +44,28 @@ var loginCmd = &cobra.Command{
 			return fmt.Errorf("unknown registry %q in %s", regName, cfgPath)
 		}
 
-		// credentials must be defined in config.yaml (or you could prompt here)
-		if reg.Username == "" || reg.Password == "" {
-			return errors.New("username/password not set in config.yaml for this registry")
+		user, pass, err := readLoginCreds(regName, reg)
+		if err != nil {
+			return err
 		}
 
-		// pick auth store path
-		authPath := cfg.AuthStore
-		if authPath == "" {
-			// default to user home (cross-platform enough for now)
-			home, _ := os.UserHomeDir()
-			authPath = filepath.Join(home, ".harair", "auth.json")
+		if !loginSkipVerify {
+			hc := harbor.New(apiURL(reg), user, pass, reg.Insecure)
+			me, err := hc.CurrentUser()
+			if err != nil {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("login to %s failed: %w", regName, err)
+			}
+			color.Green("Authenticated to %s as %s", regName, me.Username)
 		}
 
-		if err := writeAuthStore(authPath, regName, reg.Username, reg.Password); err != nil {
+		// pick auth store path (same one getCreds reads)
+		authPath, err := authStorePath(cfg)
+		if err != nil {
+			return err
+		}
+
+		if err := writeAuthStore(cfg, authPath, regName, user, pass); err != nil {
 			return err
 		}
 
@@ -50,34 +74,130 @@ var loginCmd = &cobra.Command{
 	},
 }
 
+var logoutCmd = &cobra.Command{
+	Use:   "logout [registry]",
+	Short: "Remove stored credentials for a registry from the auth store",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		regName := args[0]
+
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+		authPath, err := authStorePath(cfg)
+		if err != nil {
+			return err
+		}
+
+		removed, err := removeFromAuthStore(cfg, authPath, regName)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			color.Yellow("No stored credentials for %s in %s", regName, authPath)
+			return nil
+		}
+		color.Green("Removed credentials for %s from %s", regName, authPath)
+		return nil
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
+	loginCmd.Flags().StringVarP(&loginUsername, "username", "u", "", "Username (prompted when omitted)")
+	loginCmd.Flags().BoolVar(&loginPasswordStdin, "password-stdin", false, "Read the password from stdin")
+	loginCmd.Flags().BoolVar(&loginSkipVerify, "no-verify", false, "Store credentials without checking them against the Harbor API")
 }
 
-// simple file format: { "<registryName>": { "username": "...", "password": "..." }, ... }
-type authEntry struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-}
-type authStore map[string]authEntry
+// readLoginCreds takes the username from --username or a prompt, and the
+// password from stdin (--password-stdin) or a hidden prompt. config.yaml
+// values are only used as the prompt default / non-interactive fallback.
+func readLoginCreds(regName string, reg config.Registry) (string, string, error) {
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	in := bufio.NewReader(os.Stdin)
 
-func writeAuthStore(path, regName, user, pass string) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return err
+	user := loginUsername
+	if user == "" {
+		switch {
+		case loginPasswordStdin:
+			return "", "", errors.New("--password-stdin requires --username")
+		case interactive:
+			prompt := "Username: "
+			if reg.Username != "" {
+				prompt = fmt.Sprintf("Username [%s]: ", reg.Username)
+			}
+			fmt.Fprint(os.Stderr, prompt)
+			line, err := in.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return "", "", err
+			}
+			user = strings.TrimSpace(line)
+			if user == "" {
+				user = reg.Username
+			}
+		default:
+			user = reg.Username
+		}
 	}
-	var store authStore
-	b, err := os.ReadFile(path)
-	if err == nil {
-		_ = json.Unmarshal(b, &store)
+	if user == "" {
+		return "", "", fmt.Errorf("no username for %s: pass --username or run login in a terminal", regName)
 	}
-	if store == nil {
-		store = make(authStore)
+
+	var pass string
+	switch {
+	case loginPasswordStdin:
+		b, err := io.ReadAll(in)
+		if err != nil {
+			return "", "", err
+		}
+		pass = strings.TrimRight(string(b), "\r\n")
+	case interactive:
+		fmt.Fprint(os.Stderr, "Password: ")
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", "", err
+		}
+		pass = string(b)
+	default:
+		pass = reg.Password
 	}
-	store[regName] = authEntry{Username: user, Password: pass}
+	if pass == "" {
+		return "", "", fmt.Errorf("no password for %s: use --password-stdin or run login in a terminal", regName)
+	}
+	return user, pass, nil
+}
 
-	out, err := json.MarshalIndent(store, "", "  ")
+// writeAuthStore adds or replaces regName's entry, encrypting the store.
+func writeAuthStore(cfg *config.Config, path, regName, user, pass string) error {
+	store, encrypted, err := authstore.Load(path, authSecret(cfg, false))
+	if err != nil {
+		return err
+	}
+	store[regName] = authstore.Entry{Username: user, Password: pass}
+	// a new (or still plaintext) store gets its passphrase confirmed
+	secret, err := authSecret(cfg, !encrypted)()
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, out, 0o600)
+	return authstore.Save(path, store, secret)
+}
+
+// removeFromAuthStore deletes regName's entry and reports whether it existed.
+func removeFromAuthStore(cfg *config.Config, path, regName string) (bool, error) {
+	store, encrypted, err := authstore.Load(path, authSecret(cfg, false))
+	if err != nil {
+		return false, err
+	}
+	if _, ok := store[regName]; !ok {
+		return false, nil
+	}
+	delete(store, regName)
+	secret, err := authSecret(cfg, !encrypted)()
+	if err != nil {
+		return false, err
+	}
+	return true, authstore.Save(path, store, secret)
 }
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hakantongur/harair/internal/authstore"
+	"github.com/hakantongur/harair/internal/config"
+)
+
+// stored returns the auth store entries of cfg, decrypted with the test secret.
+func stored(t *testing.T, cfg *config.Config) authstore.Store {
+	t.Helper()
+	store, _, err := authstore.Load(cfg.AuthStore, func() ([]byte, error) { return authStoreSecret, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestLogin(t *testing.T) {
+	h := newFakeHarbor(t)
+	cfg := testConfig(t, map[string]*fakeHarbor{"h": h})
+	authStoreSecret = []byte("test passphrase")
+	t.Cleanup(func() { authStoreSecret = nil })
+
+	setStdin(t, "bad\n")
+	if code, _ := runCmd(t, cfg, "login", "h", "--username", "admin", "--password-stdin"); code != exitError {
+		t.Errorf("wrong password: exit %d, want %d", code, exitError)
+	}
+	if store := stored(t, cfg); len(store) != 0 {
+		t.Errorf("wrong password stored: %v", store)
+	}
+
+	setStdin(t, "pw\r\n")
+	if code, out := runCmd(t, cfg, "login", "h", "--username", "admin", "--password-stdin"); code != exitOK || !strings.Contains(out, "Authenticated to h as admin") {
+		t.Errorf("--password-stdin: exit %d\n%s", code, out)
+	}
+	if e := stored(t, cfg)["h"]; e != (authstore.Entry{Username: "admin", Password: "pw"}) {
+		t.Errorf("--password-stdin stored %+v", e)
+	}
+
+	setStdin(t, "pw\n")
+	if code, _ := runCmd(t, cfg, "login", "h", "--password-stdin"); code != exitError {
+		t.Errorf("--password-stdin without --username: exit %d, want %d", code, exitError)
+	}
+
+	// --no-verify stores without asking Harbor
+	h.Status = http.StatusInternalServerError
+	setStdin(t, "unchecked\n")
+	if code, out := runCmd(t, cfg, "login", "h", "--username", "robot", "--password-stdin", "--no-verify"); code != exitOK || strings.Contains(out, "Authenticated") {
+		t.Errorf("--no-verify: exit %d\n%s", code, out)
+	}
+	if e := stored(t, cfg)["h"]; e != (authstore.Entry{Username: "robot", Password: "unchecked"}) {
+		t.Errorf("--no-verify stored %+v", e)
+	}
+	h.Status = 0
+
+	// no terminal and no flags: config.yaml's credentials
+	setStdin(t, "")
+	if code, _ := runCmd(t, cfg, "login", "h"); code != exitOK {
+		t.Errorf("config.yaml fallback: exit %d", code)
+	}
+	if e := stored(t, cfg)["h"]; e != (authstore.Entry{Username: "admin", Password: "pw"}) {
+		t.Errorf("config.yaml fallback stored %+v", e)
+	}
+	reg := cfg.Registries["h"]
+	reg.Username = ""
+	cfg.Registries["h"] = reg
+	if code, _ := runCmd(t, cfg, "login", "h"); code != exitError {
+		t.Errorf("no username anywhere: exit %d, want %d", code, exitError)
+	}
+
+	if code, _ := runCmd(t, cfg, "login", "other"); code != exitError {
+		t.Errorf("unknown registry: exit %d, want %d", code, exitError)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	cfg := testConfig(t, map[string]*fakeHarbor{"h": newFakeHarbor(t)})
+	authStoreSecret = []byte("test passphrase")
+	t.Cleanup(func() { authStoreSecret = nil })
+	if err := authstore.Save(cfg.AuthStore, authstore.Store{"h": {Username: "admin", Password: "pw"}, "k": {Username: "u", Password: "p"}}, authStoreSecret); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, out := runCmd(t, cfg, "logout", "h"); code != exitOK || !strings.Contains(out, "Removed credentials for h") {
+		t.Errorf("logout: exit %d\n%s", code, out)
+	}
+	store := stored(t, cfg)
+	if _, ok := store["h"]; ok || store["k"].Username != "u" {
+		t.Errorf("after logout: %v, want k only", store)
+	}
+
+	if code, out := runCmd(t, cfg, "logout", "h"); code != exitOK || !strings.Contains(out, "No stored credentials for h") {
+		t.Errorf("second logout: exit %d\n%s", code, out)
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/fatih/color"
@@ -11,16 +12,31 @@ import (
 )
 
 var (
-	lsProject string
-	lsRepo    string
+	lsProject  string
+	lsRepo     string
+	lsOutput   string
+	lsTemplate string
+	lsAll      bool
+	lsSearch   string
+	lsQuery    string
 )
 
 var lsCmd = &cobra.Command{
 	Use:   "ls [registry]",
-	Short: "List repos or tags from a registry (via Harbor API)",
-	Args:  cobra.ExactArgs(1),
+	Short: "List projects, repos or tags from a registry (via Harbor API)",
+	Long: `List projects, repos or tags from a registry (via Harbor API).
+
+Without --project, lists all projects with repo counts and storage usage.
+--all walks projects -> repos -> artifacts (limited by --project/--repo).
+--search uses Harbor's global search for projects and repositories.
+--query passes a Harbor q filter (e.g. 'name=~web', 'tags=v1*') to the
+level being listed; with --all it filters artifacts.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		reg := args[0]
+		if err := checkOutput(lsOutput, lsTemplate); err != nil {
+			return err
+		}
 
 		cfg, err := config.Load(cfgPath)
 		if err != nil {
@@ -30,58 +46,231 @@ var lsCmd = &cobra.Command{
 		if !ok {
 			return fmt.Errorf("registry %q not found in %s", reg, cfgPath)
 		}
+		// Mocks don’t require auth—blanks are fine if no creds are found
 		user, pass, _ := getCreds(cfg, reg)
-		if err != nil {
-			// Mocks don’t require auth—fall back to blanks if no creds stored yet
-			user, pass = "", ""
-		}
-		apiBase := r.APIURL
-		if apiBase == "" {
-			apiBase = r.URL
-		}
 
-		hc := harbor.New(apiBase, user, pass, r.Insecure)
+		hc := harbor.New(apiURL(r), user, pass, r.Insecure)
 
-		if lsProject == "" {
-			color.Yellow("Tip: use --project <name> (and optionally --repo <name>)")
-			return nil
+		switch {
+		case lsSearch != "":
+			return lsSearchRegistry(hc, reg)
+		case lsAll:
+			return lsTree(hc, reg)
+		case lsProject == "":
+			return lsProjects(hc, reg)
 		}
 
 		if lsRepo == "" {
-			repos, err := hc.ListRepos(lsProject)
+			repos, err := hc.ListReposQuery(lsProject, lsQuery)
 			if err != nil {
 				return err
 			}
-			color.Cyan("%s/%s repos:", reg, lsProject)
-			for _, rr := range repos {
+			for i := range repos {
 				// API returns "<project>/<repo>"
-				name := rr.Name
-				if parts := strings.SplitN(rr.Name, "/", 2); len(parts) == 2 {
-					name = parts[1]
+				if parts := strings.SplitN(repos[i].Name, "/", 2); len(parts) == 2 {
+					repos[i].Name = parts[1]
 				}
-				fmt.Println("-", name)
 			}
-			return nil
+			if isTable(lsOutput, lsTemplate) {
+				color.Cyan("%s/%s repos:", reg, lsProject)
+			}
+			return printItems(lsOutput, lsTemplate, repos, func(w io.Writer) {
+				fmt.Fprintln(w, "NAME\tARTIFACTS\tPULLS\tUPDATED")
+				for _, rr := range repos {
+					fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", rr.Name, rr.ArtifactCount, rr.PullCount, shortTime(rr.UpdateTime))
+				}
+			})
 		}
 
-		arts, err := hc.ListArtifacts(lsProject, lsRepo)
+		arts, err := hc.ListArtifactsQuery(lsProject, lsRepo, lsQuery)
 		if err != nil {
 			return err
 		}
-		color.Cyan("%s/%s/%s artifacts:", reg, lsProject, lsRepo)
-		for _, a := range arts {
-			var tags []string
-			for _, t := range a.Tags {
-				tags = append(tags, t.Name)
-			}
-			fmt.Printf("digest=%s tags=%v\n", a.Digest, tags)
+		if isTable(lsOutput, lsTemplate) {
+			color.Cyan("%s/%s/%s artifacts:", reg, lsProject, lsRepo)
 		}
-		return nil
+		return printItems(lsOutput, lsTemplate, arts, func(w io.Writer) {
+			if lsOutput == outputWide {
+				fmt.Fprintln(w, "DIGEST\tTAGS\tSIZE\tTYPE\tPUSHED\tPULLED\tLABELS\tMEDIA TYPE")
+			} else {
+				fmt.Fprintln(w, "DIGEST\tTAGS\tSIZE\tPUSHED")
+			}
+			for _, a := range arts {
+				tags := orDash(strings.Join(a.TagNames(), ","))
+				if lsOutput != outputWide {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", shortDigest(a.Digest), tags, humanSize(a.Size), shortTime(a.PushTime))
+					continue
+				}
+				var labels []string
+				for _, l := range a.Labels {
+					labels = append(labels, l.Name)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", a.Digest, tags, humanSize(a.Size), orDash(a.Type),
+					shortTime(a.PushTime), shortTime(a.PullTime), orDash(strings.Join(labels, ",")), orDash(a.ManifestMediaType))
+			}
+		})
 	},
 }
 
+// projectRow is a project with its storage usage, as listed by ls.
+type projectRow struct {
+	harbor.Project `yaml:",inline"`
+	StorageUsed    int64 `json:"storage_used" yaml:"storage_used"`
+	StorageLimit   int64 `json:"storage_limit" yaml:"storage_limit"` // -1: unlimited
+}
+
+func lsProjects(hc *harbor.Client, reg string) error {
+	projects, err := hc.ListProjects(lsQuery)
+	if err != nil {
+		return err
+	}
+	// quotas need admin rights on most installs; without them, usage is unknown
+	quotas, qerr := hc.ListProjectQuotas()
+	if qerr != nil && verbose {
+		color.Yellow("storage usage unavailable: %v", qerr)
+	}
+	rows := make([]projectRow, len(projects))
+	for i, p := range projects {
+		rows[i] = projectRow{Project: p, StorageUsed: -1, StorageLimit: -1}
+		if q, ok := quotas[p.Name]; ok {
+			rows[i].StorageUsed = q.Used["storage"]
+			rows[i].StorageLimit = q.Hard["storage"]
+		}
+	}
+
+	if isTable(lsOutput, lsTemplate) {
+		color.Cyan("%s projects:", reg)
+	}
+	return printItems(lsOutput, lsTemplate, rows, func(w io.Writer) {
+		fmt.Fprintln(w, "NAME\tPUBLIC\tREPOS\tCHARTS\tSTORAGE\tQUOTA\tUPDATED")
+		for _, r := range rows {
+			fmt.Fprintf(w, "%s\t%t\t%d\t%d\t%s\t%s\t%s\n", r.Name, r.Public(), r.RepoCount, r.ChartCount,
+				storage(r.StorageUsed, "-"), storage(r.StorageLimit, "unlimited"), shortTime(r.UpdateTime))
+		}
+	})
+}
+
+// storage formats a byte count, or none when it is negative (unknown/unlimited).
+func storage(n int64, none string) string {
+	if n < 0 {
+		return none
+	}
+	return humanSize(n)
+}
+
+// treeRepo and treeProject are the nodes of ls --all.
+type treeRepo struct {
+	harbor.Repository `yaml:",inline"`
+	Artifacts         []harbor.Artifact `json:"artifacts" yaml:"artifacts"`
+}
+
+type treeProject struct {
+	Name         string     `json:"name" yaml:"name"`
+	Repositories []treeRepo `json:"repositories" yaml:"repositories"`
+}
+
+func lsTree(hc *harbor.Client, reg string) error {
+	var names []string
+	if lsProject != "" {
+		names = []string{lsProject}
+	} else {
+		projects, err := hc.ListProjects("")
+		if err != nil {
+			return err
+		}
+		for _, p := range projects {
+			names = append(names, p.Name)
+		}
+	}
+
+	var tree []treeProject
+	for _, name := range names {
+		node := treeProject{Name: name}
+		repos, err := hc.ListRepos(name)
+		if err != nil {
+			return err
+		}
+		for _, rr := range repos {
+			rr.Name = repoName(rr.Name)
+			if lsRepo != "" && rr.Name != lsRepo {
+				continue
+			}
+			arts, err := hc.ListArtifactsQuery(name, rr.Name, lsQuery)
+			if err != nil {
+				return err
+			}
+			node.Repositories = append(node.Repositories, treeRepo{Repository: rr, Artifacts: arts})
+		}
+		tree = append(tree, node)
+	}
+
+	if isTable(lsOutput, lsTemplate) {
+		color.Cyan("%s:", reg)
+	}
+	return printItems(lsOutput, lsTemplate, tree, func(w io.Writer) {
+		for _, p := range tree {
+			fmt.Fprintf(w, "%s/\t\t\t\n", p.Name)
+			for _, rr := range p.Repositories {
+				fmt.Fprintf(w, "  %s\t\t\t\n", rr.Name)
+				for _, a := range rr.Artifacts {
+					fmt.Fprintf(w, "    %s\t%s\t%s\t%s\n", shortDigest(a.Digest),
+						orDash(strings.Join(a.TagNames(), ",")), humanSize(a.Size), shortTime(a.PushTime))
+				}
+			}
+		}
+	})
+}
+
+// searchHit is one project or repository found by ls --search.
+type searchHit struct {
+	Kind      string `json:"kind" yaml:"kind"` // project or repository
+	Name      string `json:"name" yaml:"name"`
+	Project   string `json:"project" yaml:"project"`
+	Public    bool   `json:"public" yaml:"public"`
+	Artifacts int    `json:"artifact_count,omitempty" yaml:"artifact_count,omitempty"`
+	Pulls     int    `json:"pull_count,omitempty" yaml:"pull_count,omitempty"`
+}
+
+func lsSearchRegistry(hc *harbor.Client, reg string) error {
+	res, err := hc.Search(lsSearch)
+	if err != nil {
+		return err
+	}
+	var hits []searchHit
+	for _, p := range res.Projects {
+		hits = append(hits, searchHit{Kind: "project", Name: p.Name, Project: p.Name, Public: p.Public()})
+	}
+	for _, r := range res.Repositories {
+		hits = append(hits, searchHit{Kind: "repository", Name: r.RepositoryName, Project: r.ProjectName,
+			Public: r.ProjectPublic, Artifacts: r.ArtifactCount, Pulls: r.PullCount})
+	}
+
+	if isTable(lsOutput, lsTemplate) {
+		color.Cyan("%s search %q:", reg, lsSearch)
+	}
+	return printItems(lsOutput, lsTemplate, hits, func(w io.Writer) {
+		fmt.Fprintln(w, "KIND\tNAME\tPUBLIC\tARTIFACTS\tPULLS")
+		for _, h := range hits {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%d\n", h.Kind, h.Name, h.Public, h.Artifacts, h.Pulls)
+		}
+	})
+}
+
+// shortDigest abbreviates sha256:<hex> to its first 12 hex digits for tables.
+func shortDigest(d string) string {
+	if algo, hex, ok := strings.Cut(d, ":"); ok && len(hex) > 12 {
+		return algo + ":" + hex[:12]
+	}
+	return d
+}
+
 func init() {
 	rootCmd.AddCommand(lsCmd)
 	lsCmd.Flags().StringVar(&lsProject, "project", "", "Project to list")
 	lsCmd.Flags().StringVar(&lsRepo, "repo", "", "Repo to list tags for")
+	lsCmd.Flags().BoolVar(&lsAll, "all", false, "Walk the full project -> repo -> artifact tree")
+	lsCmd.Flags().StringVar(&lsSearch, "search", "", "Search projects and repositories by name")
+	lsCmd.Flags().StringVarP(&lsQuery, "query", "q", "", "Harbor q filter for the listed level, e.g. 'name=~web'")
+	lsCmd.Flags().StringVarP(&lsOutput, "output", "o", outputTable, "Output format: table, wide, json or yaml")
+	lsCmd.Flags().StringVar(&lsTemplate, "template", "", "Go template applied to each item, e.g. '{{.Digest}} {{join .TagNames \",\"}}'")
 }
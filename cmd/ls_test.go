@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLsProjects(t *testing.T) {
+	h := newFakeHarbor(t)
+	h.project("core", map[string]string{"public": "true"}, 1<<20, -1)
+	h.project("uruk", nil, 5<<20, 1<<30)
+	h.add("uruk", "app", imageArt("sha256:a", "v1"))
+	cfg := testConfig(t, map[string]*fakeHarbor{"h": h})
+
+	type row struct {
+		Name         string            `json:"name"`
+		RepoCount    int               `json:"repo_count"`
+		Metadata     map[string]string `json:"metadata"`
+		StorageUsed  int64             `json:"storage_used"`
+		StorageLimit int64             `json:"storage_limit"`
+	}
+	list := func() []row {
+		t.Helper()
+		code, out := runCmd(t, cfg, "ls", "h", "-o", "json")
+		var rows []row
+		if err := json.Unmarshal([]byte(out), &rows); code != exitOK || err != nil {
+			t.Fatalf("exit %d, %v\n%s", code, err, out)
+		}
+		return rows
+	}
+
+	rows := list()
+	if len(rows) != 2 || rows[0].Name != "core" || rows[0].Metadata["public"] != "true" ||
+		rows[0].StorageUsed != 1<<20 || rows[0].StorageLimit != -1 ||
+		rows[1].Name != "uruk" || rows[1].RepoCount != 1 || rows[1].StorageUsed != 5<<20 || rows[1].StorageLimit != 1<<30 {
+		t.Errorf("projects %+v", rows)
+	}
+
+	// without admin rights there are no quotas: usage is unknown, not zero
+	h.QuotaStatus = http.StatusForbidden
+	for _, r := range list() {
+		if r.StorageUsed != -1 || r.StorageLimit != -1 {
+			t.Errorf("%s without quotas: used %d, limit %d, want -1", r.Name, r.StorageUsed, r.StorageLimit)
+		}
+	}
+	code, out := runCmd(t, cfg, "ls", "h")
+	if code != exitOK || !strings.Contains(out, "NAME") || !strings.Contains(out, "uruk") {
+		t.Errorf("table: exit %d\n%s", code, out)
+	}
+}
+
+func TestLsTree(t *testing.T) {
+	h := newFakeHarbor(t)
+	h.add("core", "base", imageArt("sha256:b", "1"))
+	h.add("uruk", "app", imageArt("sha256:a", "v1"))
+	h.add("uruk", "web", imageArt("sha256:w", "v1"))
+	cfg := testConfig(t, map[string]*fakeHarbor{"h": h})
+
+	var tree []struct {
+		Name         string `yaml:"name"`
+		Repositories []struct {
+			Name      string `yaml:"name"`
+			Artifacts []struct {
+				Digest string `yaml:"digest"`
+			} `yaml:"artifacts"`
+		} `yaml:"repositories"`
+	}
+	code, out := runCmd(t, cfg, "ls", "h", "--all", "-o", "yaml")
+	if err := yaml.Unmarshal([]byte(out), &tree); code != exitOK || err != nil {
+		t.Fatalf("exit %d, %v\n%s", code, err, out)
+	}
+	if len(tree) != 2 || tree[1].Name != "uruk" || len(tree[1].Repositories) != 2 ||
+		tree[1].Repositories[0].Name != "app" || tree[1].Repositories[0].Artifacts[0].Digest != "sha256:a" {
+		t.Errorf("tree %+v", tree)
+	}
+
+	code, out = runCmd(t, cfg, "ls", "h", "--all", "--project", "uruk", "--repo", "web",
+		"--template", "{{.Name}}{{range .Repositories}} {{.Name}}={{len .Artifacts}}{{end}}")
+	if code != exitOK || out != "uruk web=1\n" {
+		t.Errorf("limited tree: exit %d, %q", code, out)
+	}
+
+	h.Fail["projects/uruk/repositories/web"] = http.StatusInternalServerError
+	if code, _ := runCmd(t, cfg, "ls", "h", "--all"); code != exitError {
+		t.Errorf("listing error: exit %d, want %d", code, exitError)
+	}
+}
+
+func TestLsSearch(t *testing.T) {
+	h := newFakeHarbor(t)
+	h.project("web", map[string]string{"public": "true"}, 0, -1)
+	h.add("web", "frontend", imageArt("sha256:f", "v1"))
+	h.add("uruk", "webapp", imageArt("sha256:a", "v1"))
+	h.add("uruk", "db", imageArt("sha256:d", "v1"))
+	cfg := testConfig(t, map[string]*fakeHarbor{"h": h})
+
+	code, out := runCmd(t, cfg, "ls", "h", "--search", "web", "-o", "json")
+	var hits []searchHit
+	if err := json.Unmarshal([]byte(out), &hits); code != exitOK || err != nil {
+		t.Fatalf("exit %d, %v\n%s", code, err, out)
+	}
+	want := []searchHit{
+		{Kind: "project", Name: "web", Project: "web", Public: true},
+		{Kind: "repository", Name: "uruk/webapp", Project: "uruk", Artifacts: 1},
+		{Kind: "repository", Name: "web/frontend", Project: "web", Public: true, Artifacts: 1},
+	}
+	if len(hits) != len(want) {
+		t.Fatalf("hits %+v", hits)
+	}
+	for i := range want {
+		if hits[i] != want[i] {
+			t.Errorf("hit %d: %+v, want %+v", i, hits[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/harbor"
+	"github.com/hakantongur/harair/internal/report"
+	"github.com/hakantongur/harair/internal/rules"
+	"golang.org/x/term"
+)
+
+// Sync modes (--mode).
+const (
+	modeCopy   = "copy"   // only add and update
+	modeMirror = "mirror" // also delete what is gone from the source
+)
+
+// kindDelete is the report kind of a mirror deletion.
+const kindDelete = "delete"
+
+// mirrorDelete is a destination artifact losing some (or all) of its tags
+// because they no longer exist on the source.
+type mirrorDelete struct {
+	Project string
+	Repo    string
+	Digest  string
+	Size    int64
+	Tags    []string // tags to remove
+	All     bool     // no tags remain: delete the artifact itself
+}
+
+// planDeletions finds destination tags within the scope of the image plans
+// (the mapped project and repo, and a tag that maps back to a source tag
+// matching the globs and not excluded by the rule set) that none of the
+// sources has any more. Repos that are gone from the source entirely are out
+// of scope; charts are left alone. Any listing error aborts, so a source
+// outage never reads as "everything was deleted".
+func planDeletions(order []string, sources map[string]*registrySource, plans map[string]*sourcePlan,
+	rs *rules.RuleSet, dstHC *harbor.Client) ([]mirrorDelete, error) {
+
+	type scopeItem struct {
+		from          string
+		project, repo string // on the source
+		tags          []string
+		mapping       *rules.Mapping
+	}
+	scopes := map[[2]string][]scopeItem{}
+	onSource := map[[2]string]map[string]bool{} // destination tags and digests
+	var keys [][2]string
+	for _, name := range order {
+		for _, item := range plans[name].Images {
+			project, repo, err := item.Mapping.Path(item.Project, item.Repo)
+			if err != nil {
+				return nil, fmt.Errorf("mirror: %w", err)
+			}
+			key := [2]string{project, repo}
+			if _, ok := onSource[key]; !ok {
+				onSource[key] = map[string]bool{}
+				keys = append(keys, key)
+			}
+			scopes[key] = append(scopes[key], scopeItem{from: name, project: item.Project, repo: item.Repo,
+				tags: item.Tags, mapping: item.Mapping})
+
+			arts, err := sources[name].HC.ListArtifacts(item.Project, item.Repo)
+			if err != nil {
+				return nil, fmt.Errorf("mirror: list %s %s/%s: %w", name, item.Project, item.Repo, err)
+			}
+			for _, a := range arts {
+				onSource[key][a.Digest] = true
+				for _, t := range a.Tags {
+					if dt, err := item.Mapping.TagOf(item.Project, item.Repo, t.Name); err == nil {
+						onSource[key][dt] = true
+					}
+				}
+			}
+		}
+	}
+
+	var dels []mirrorDelete
+	for _, key := range keys {
+		project, repo := key[0], key[1]
+		arts, err := dstHC.ListArtifacts(project, repo)
+		if harbor.IsNotFound(err) {
+			continue // not on the destination yet
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mirror: list destination %s/%s: %w", project, repo, err)
+		}
+		for _, a := range arts {
+			if a.IsChart() || len(a.Tags) == 0 {
+				continue
+			}
+			d := mirrorDelete{Project: project, Repo: repo, Digest: a.Digest, Size: a.Size}
+			for _, t := range a.Tags {
+				if onSource[key][t.Name] {
+					continue
+				}
+				for _, s := range scopes[key] {
+					// globs and excludes are written against source tags; a tag
+					// the template can't map back is left alone
+					src, ok := s.mapping.SourceTag(s.project, s.repo, t.Name)
+					if !ok || !globAny(s.tags, src) {
+						continue
+					}
+					if rs != nil && excludedByRuleSet(rs, s.from, planArtifact{Project: s.project, Repo: s.repo, Tag: src}) {
+						continue
+					}
+					d.Tags = append(d.Tags, t.Name)
+					break
+				}
+			}
+			if len(d.Tags) > 0 {
+				sort.Strings(d.Tags)
+				// keep artifacts the source still has: a copy may tag them again
+				d.All = len(d.Tags) == len(a.Tags) && !onSource[key][a.Digest]
+				dels = append(dels, d)
+			}
+		}
+	}
+	sort.Slice(dels, func(i, j int) bool {
+		if dels[i].Project+"/"+dels[i].Repo != dels[j].Project+"/"+dels[j].Repo {
+			return dels[i].Project+"/"+dels[i].Repo < dels[j].Project+"/"+dels[j].Repo
+		}
+		return dels[i].Tags[0] < dels[j].Tags[0]
+	})
+	return dels, nil
+}
+
+// countTags is the number of tags dels remove, as limited by --max-deletes.
+func countTags(dels []mirrorDelete) int {
+	n := 0
+	for _, d := range dels {
+		n += len(d.Tags)
+	}
+	return n
+}
+
+// previewDeletions prints every deletion; it runs before any change is made.
+func previewDeletions(dels []mirrorDelete, dstHost, toReg string) {
+	if len(dels) == 0 {
+		color.Cyan("Mirror: nothing to delete on %s.", toReg)
+		return
+	}
+	color.Cyan("Mirror: %d tag(s) on %s no longer exist on the source:", countTags(dels), toReg)
+	for _, d := range dels {
+		for _, t := range d.Tags {
+			color.Red("[delete] docker://%s/%s/%s:%s", dstHost, d.Project, d.Repo, t)
+		}
+		if d.All {
+			color.Red("[delete] docker://%s/%s/%s@%s (artifact, %s; no tags left)", dstHost, d.Project, d.Repo, d.Digest, humanSize(d.Size))
+		}
+	}
+}
+
+// confirmDeletions asks before deleting, unless --yes was given. Without a
+// terminal to ask on, it refuses.
+func confirmDeletions(n int, toReg string, yes bool) error {
+	if yes {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("refusing to delete %d tag(s) on %s without confirmation: review the dry-run and pass --yes", n, toReg)
+	}
+	fmt.Fprintf(os.Stderr, "Delete %d tag(s) on %s? [y/N]: ", n, toReg)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(line)); a != "y" && a != "yes" {
+		return fmt.Errorf("deletions not confirmed")
+	}
+	return nil
+}
+
+// runDeletions removes dels from the destination: the tags, or the whole
+// artifact when none of its tags remain. It returns how many tags it failed to
+// delete.
+func runDeletions(dstHC *harbor.Client, dels []mirrorDelete, dstHost string, rep *taskReport) (failed int) {
+	record := func(d mirrorDelete, ref string, tags int, start time.Time, err error) {
+		rt := report.Task{Kind: kindDelete, Destination: ref, Digest: d.Digest, Duration: time.Since(start), Outcome: copySucceeded.String()}
+		if err != nil {
+			failed += tags
+			rt.Outcome, rt.Error = copyFailed.String(), err.Error()
+			color.Red("delete failed: %s: %v", ref, err)
+		} else if verbose {
+			color.Yellow("deleted: %s", ref)
+		}
+		rep.add(rt)
+	}
+
+	for _, d := range dels {
+		start := time.Now()
+		if d.All {
+			ref := fmt.Sprintf("docker://%s/%s/%s@%s", dstHost, d.Project, d.Repo, d.Digest)
+			record(d, ref, len(d.Tags), start, dstHC.DeleteArtifact(d.Project, d.Repo, d.Digest))
+			continue
+		}
+		for _, t := range d.Tags {
+			ref := fmt.Sprintf("docker://%s/%s/%s:%s", dstHost, d.Project, d.Repo, t)
+			record(d, ref, 1, start, dstHC.DeleteTag(d.Project, d.Repo, d.Digest, t))
+			start = time.Now()
+		}
+	}
+	return failed
+}
+
+// plannedDeletions records dels in a dry-run report.
+func plannedDeletions(dels []mirrorDelete, dstHost string, rep *taskReport) {
+	for _, d := range dels {
+		if d.All {
+			rep.add(report.Task{Kind: kindDelete, Destination: fmt.Sprintf("docker://%s/%s/%s@%s", dstHost, d.Project, d.Repo, d.Digest),
+				Digest: d.Digest, Outcome: report.Planned})
+			continue
+		}
+		for _, t := range d.Tags {
+			rep.add(report.Task{Kind: kindDelete, Destination: fmt.Sprintf("docker://%s/%s/%s:%s", dstHost, d.Project, d.Repo, t),
+				Digest: d.Digest, Outcome: report.Planned})
+		}
+	}
+}
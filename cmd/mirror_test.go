@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hakantongur/harair/internal/rules"
+)
+
+// mirrorFixture is a source with uruk/aed/x mapped to mirror/x on the
+// destination, whose tags have drifted in every way planDeletions cares about.
+func mirrorFixture(t *testing.T) (src, dst *fakeHarbor, plans map[string]*sourcePlan, rs *rules.RuleSet) {
+	t.Helper()
+	src, dst = newFakeHarbor(t), newFakeHarbor(t)
+	src.add("uruk", "aed/x", imageArt("sha256:a", "v1"))
+	src.add("uruk", "aed/x", imageArt("sha256:b", "v2"))
+	src.add("uruk", "aed/x", imageArt("sha256:f", "v7"))
+
+	dst.add("mirror", "x", imageArt("sha256:a", "v1-airgap"))              // on the source
+	dst.add("mirror", "x", imageArt("sha256:old", "v0-airgap"))            // gone: artifact and all
+	dst.add("mirror", "x", imageArt("sha256:b", "v2-airgap", "v9-airgap")) // v9 gone, v2 stays
+	dst.add("mirror", "x", imageArt("sha256:f", "v4-airgap"))              // tag moved: digest stays
+	dst.add("mirror", "x", imageArt("sha256:c", "latest-airgap"))          // outside the v* glob
+	dst.add("mirror", "x", imageArt("sha256:d", "v3"))                     // not made by the template
+	dst.add("mirror", "x", imageArt("sha256:e", "v5-rc-airgap"))           // excluded by the rule set
+	dst.add("mirror", "gone", imageArt("sha256:g", "v0-airgap"))           // repo not on the source
+	dst.add("uruk", "aed/x", imageArt("sha256:u", "v0"))                   // unmapped name: other scope
+	chart := imageArt("sha256:h", "v0-airgap")
+	chart.Type = "CHART"
+	dst.add("mirror", "x", chart)
+
+	m := &rules.Mapping{Project: "mirror", StripPrefix: "aed/", Tag: "{{.Tag}}-airgap"}
+	plans = map[string]*sourcePlan{"src": {Images: []planItem{{Project: "uruk", Repo: "aed/x", Tags: []string{"v*"}, Mapping: m}}}}
+	rs = &rules.RuleSet{Exclude: []rules.Entry{{Image: &rules.ImageInclude{Project: "uruk", Tags: []string{"*-rc"}}}}}
+	return src, dst, plans, rs
+}
+
+func TestPlanDeletions(t *testing.T) {
+	src, dst, plans, rs := mirrorFixture(t)
+	sources := map[string]*registrySource{"src": {Name: "src", HC: src.client()}}
+
+	dels, err := planDeletions([]string{"src"}, sources, plans, rs, dst.client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range dels {
+		got = append(got, fmt.Sprintf("%s/%s@%s %v all=%t", d.Project, d.Repo, d.Digest, d.Tags, d.All))
+	}
+	want := []string{
+		"mirror/x@sha256:old [v0-airgap] all=true",
+		"mirror/x@sha256:f [v4-airgap] all=false",
+		"mirror/x@sha256:b [v9-airgap] all=false",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("deletions\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if n := countTags(dels); n != 3 {
+		t.Errorf("countTags = %d", n)
+	}
+
+	// without the rule set, the excluded tag goes too
+	dels, _ = planDeletions([]string{"src"}, sources, plans, nil, dst.client())
+	if n := countTags(dels); n != 4 {
+		t.Errorf("no rule set: %d tag(s), want 4", n)
+	}
+}
+
+func TestPlanDeletionsListError(t *testing.T) {
+	for _, side := range []string{"source", "destination"} {
+		src, dst, plans, rs := mirrorFixture(t)
+		if side == "source" {
+			src.Fail["projects/uruk"] = http.StatusInternalServerError
+		} else {
+			dst.Fail["projects/mirror"] = http.StatusInternalServerError
+		}
+		sources := map[string]*registrySource{"src": {Name: "src", HC: src.client()}}
+		dels, err := planDeletions([]string{"src"}, sources, plans, rs, dst.client())
+		if err == nil || len(dels) != 0 {
+			t.Errorf("%s listing fails: %d deletion(s), err %v", side, len(dels), err)
+		}
+	}
+}
+
+func TestRunDeletions(t *testing.T) {
+	src, dst, plans, rs := mirrorFixture(t)
+	sources := map[string]*registrySource{"src": {Name: "src", HC: src.client()}}
+	dels, err := planDeletions([]string{"src"}, sources, plans, rs, dst.client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := runDeletions(dst.client(), dels, "dst", nil); failed != 0 {
+		t.Errorf("%d failed", failed)
+	}
+	want := []string{
+		"DELETE projects/mirror/repositories/x/artifacts/sha256:old",
+		"DELETE projects/mirror/repositories/x/artifacts/sha256:f/tags/v4-airgap",
+		"DELETE projects/mirror/repositories/x/artifacts/sha256:b/tags/v9-airgap",
+	}
+	if got := dst.Calls(); !slices.Equal(got, want) {
+		t.Errorf("calls\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	tags := dst.tags("mirror", "x")
+	if _, ok := tags["sha256:old"]; ok || len(tags["sha256:f"]) != 0 || !slices.Equal(tags["sha256:b"], []string{"v2-airgap"}) {
+		t.Errorf("destination after: %v", tags)
+	}
+
+	dst.Status = http.StatusForbidden
+	if failed := runDeletions(dst.client(), dels, "dst", nil); failed != 3 {
+		t.Errorf("forbidden: %d failed, want 3", failed)
+	}
+}
+
+func TestSyncMirror(t *testing.T) {
+	src, dst := newFakeHarbor(t), newFakeHarbor(t)
+	src.add("p", "app", imageArt("sha256:a", "v1"))
+	dst.add("p", "app", imageArt("sha256:a", "v1"))
+	dst.add("p", "app", imageArt("sha256:old", "v0", "v00"))
+	cfg := testConfig(t, map[string]*fakeHarbor{"src": src, "dst": dst})
+	cfg.SkopeoPath = "native"
+	args := []string{"sync", "src", "dst", "--project", "p", "--mode", "mirror"}
+
+	code, out := runCmd(t, cfg, args...)
+	if code != exitOK || !strings.Contains(out, "[delete] docker://"+dst.Listener.Addr().String()+"/p/app:v0") {
+		t.Errorf("dry-run: exit %d\n%s", code, out)
+	}
+	if calls := dst.Calls(); len(calls) != 0 {
+		t.Errorf("dry-run changed the destination: %v", calls)
+	}
+
+	if code, _ := runCmd(t, cfg, append(args, "--dry-run=false", "--yes", "--max-deletes", "1")...); code != exitError {
+		t.Errorf("over --max-deletes: exit %d, want %d", code, exitError)
+	}
+	if calls := dst.Calls(); len(calls) != 0 {
+		t.Errorf("refused run changed the destination: %v", calls)
+	}
+
+	code, _ = runCmd(t, cfg, append(args, "--dry-run=false", "--yes", "--journal", t.TempDir()+"/j.jsonl")...)
+	if calls := dst.Calls(); code != exitOK || !slices.Equal(calls, []string{"DELETE projects/p/repositories/app/artifacts/sha256:old"}) {
+		t.Errorf("real run: exit %d, calls %v", code, calls)
+	}
+	if _, ok := dst.tags("p", "app")["sha256:old"]; ok {
+		t.Error("artifact still there")
+	}
+}
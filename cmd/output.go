@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output.
+const (
+	outputTable = "table"
+	outputWide  = "wide"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// checkOutput validates an --output/--template combination.
+func checkOutput(format, tmpl string) error {
+	switch format {
+	case outputTable, outputWide, outputJSON, outputYAML:
+	default:
+		return fmt.Errorf("invalid --output %q: want table, wide, json or yaml", format)
+	}
+	if tmpl != "" {
+		if _, err := newTemplate(tmpl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTable reports whether output is for people (table or wide, no template).
+func isTable(format, tmpl string) bool {
+	return tmpl == "" && (format == outputTable || format == outputWide)
+}
+
+// printItems writes items (a slice) to stdout: through tmpl once per item
+// when set, as JSON or YAML, or else as a table drawn by table.
+func printItems(format, tmpl string, items any, table func(w io.Writer)) error {
+	if tmpl != "" {
+		t, err := newTemplate(tmpl)
+		if err != nil {
+			return err
+		}
+		v := reflect.ValueOf(items)
+		for i := 0; i < v.Len(); i++ {
+			if err := t.Execute(os.Stdout, v.Index(i).Interface()); err != nil {
+				return err
+			}
+			fmt.Println()
+		}
+		return nil
+	}
+
+	switch format {
+	case outputJSON:
+		if reflect.ValueOf(items).IsNil() {
+			items = []struct{}{} // print [] rather than null
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case outputYAML:
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(items)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+// newTemplate parses a --template, with a few helpers for common fields.
+func newTemplate(text string) (*template.Template, error) {
+	t, err := template.New("output").Funcs(template.FuncMap{
+		"join":      strings.Join,
+		"humanSize": humanSize,
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --template: %w", err)
+	}
+	return t, nil
+}
+
+// humanSize formats a byte count as B, KiB, MiB, ...
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// shortTime formats t for tables, or "-" when unset.
+func shortTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// orDash returns s, or "-" when it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLsOutput(t *testing.T) {
+	h := newFakeHarbor(t)
+	h.add("p", "app", imageArt("sha256:a", "v1", "latest"))
+	h.add("p", "app", imageArt("sha256:b"))
+	h.add("p", "tools/cli", imageArt("sha256:c", "v2"))
+	cfg := testConfig(t, map[string]*fakeHarbor{"h": h})
+
+	// json: the API's fields, repo names without the project
+	code, out := runCmd(t, cfg, "ls", "h", "--project", "p", "-o", "json")
+	var repos []struct {
+		Name          string `json:"name"`
+		ArtifactCount int    `json:"artifact_count"`
+	}
+	if err := json.Unmarshal([]byte(out), &repos); code != exitOK || err != nil {
+		t.Fatalf("json repos: exit %d, %v\n%s", code, err, out)
+	}
+	if len(repos) != 2 || repos[0].Name != "app" || repos[0].ArtifactCount != 2 || repos[1].Name != "tools/cli" {
+		t.Errorf("json repos %+v", repos)
+	}
+
+	// yaml
+	code, out = runCmd(t, cfg, "ls", "h", "--project", "p", "--repo", "app", "-o", "yaml")
+	var arts []struct {
+		Digest string `yaml:"digest"`
+		Tags   []struct {
+			Name string `yaml:"name"`
+		} `yaml:"tags"`
+	}
+	if err := yaml.Unmarshal([]byte(out), &arts); code != exitOK || err != nil {
+		t.Fatalf("yaml artifacts: exit %d, %v\n%s", code, err, out)
+	}
+	if len(arts) != 2 || arts[0].Digest != "sha256:a" || len(arts[0].Tags) != 2 || len(arts[1].Tags) != 0 {
+		t.Errorf("yaml artifacts %+v", arts)
+	}
+
+	// template: once per item, with the helpers, and no table heading
+	code, out = runCmd(t, cfg, "ls", "h", "--project", "p", "--repo", "app",
+		"--template", `{{.Digest}} {{join .TagNames ","}} {{humanSize .Size}}`)
+	if want := "sha256:a v1,latest 10 B\nsha256:b  10 B\n"; code != exitOK || out != want {
+		t.Errorf("template: exit %d\n%q\nwant\n%q", code, out, want)
+	}
+
+	// table
+	code, out = runCmd(t, cfg, "ls", "h", "--project", "p", "--repo", "app")
+	if code != exitOK || !strings.Contains(out, "h/p/app artifacts:") || !strings.Contains(out, "DIGEST") || !strings.Contains(out, "v1,latest") {
+		t.Errorf("table: exit %d\n%s", code, out)
+	}
+}
+
+func TestPrintItemsEmpty(t *testing.T) {
+	h := newFakeHarbor(t)
+	cfg := testConfig(t, map[string]*fakeHarbor{"h": h})
+	code, out := runCmd(t, cfg, "ls", "h", "--search", "nothing", "-o", "json")
+	if code != exitOK || strings.TrimSpace(out) != "[]" {
+		t.Errorf("no items: exit %d, %q, want []", code, out)
+	}
+	code, out = runCmd(t, cfg, "ls", "h", "--search", "nothing", "-o", "yaml")
+	if code != exitOK || strings.TrimSpace(out) != "[]" {
+		t.Errorf("no items, yaml: exit %d, %q, want []", code, out)
+	}
+}
+
+func TestCheckOutput(t *testing.T) {
+	for _, tc := range []struct {
+		format, tmpl string
+		ok           bool
+	}{
+		{outputTable, "", true},
+		{outputWide, "", true},
+		{outputJSON, "", true},
+		{outputYAML, "", true},
+		{"xml", "", false},
+		{outputTable, "{{.Name}}", true},
+		{outputTable, "{{.Name", false},
+		{outputTable, "{{nosuchfunc .Name}}", false},
+	} {
+		if err := checkOutput(tc.format, tc.tmpl); (err == nil) != tc.ok {
+			t.Errorf("checkOutput(%q, %q) = %v", tc.format, tc.tmpl, err)
+		}
+	}
+
+	cfg := testConfig(t, map[string]*fakeHarbor{"h": newFakeHarbor(t)})
+	if code, _ := runCmd(t, cfg, "ls", "h", "-o", "xml"); code != exitError {
+		t.Errorf("-o xml: exit %d, want %d", code, exitError)
+	}
+}
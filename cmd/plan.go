@@ -0,0 +1,363 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/harbor"
+	"github.com/hakantongur/harair/internal/rules"
+)
+
+// planItem is one repo of a project together with the tag globs to take from it.
+type planItem struct {
+	Project   string
+	Repo      string
+	Tags      []string
+	Platforms []string       // from the rule; empty => the command's --platforms
+	Mapping   *rules.Mapping // destination names; nil => same as the source
+}
+
+// chartItem selects Helm chart versions from a project by name and version globs.
+type chartItem struct {
+	Project  string
+	Name     string
+	Versions []string
+}
+
+// sourcePlan is everything to take from one source registry.
+type sourcePlan struct {
+	Images []planItem
+	Charts []chartItem
+}
+
+// registrySource is a source registry from config.yaml with its API client.
+type registrySource struct {
+	Name string
+	Reg  config.Registry
+	User string
+	Pass string
+	HC   *harbor.Client
+}
+
+func newRegistrySource(cfg *config.Config, name string) (*registrySource, error) {
+	r, ok := cfg.Registries[name]
+	if !ok {
+		return nil, fmt.Errorf("registry %q not in %s", name, cfgPath)
+	}
+	user, pass, _ := getCreds(cfg, name) // optional (mocks may not need)
+	api := apiURL(r)
+	if strings.TrimSpace(api) == "" {
+		return nil, fmt.Errorf("registry %q: api_url/url is empty in config.yaml", name)
+	}
+	return &registrySource{
+		Name: name,
+		Reg:  r,
+		User: user,
+		Pass: pass,
+		HC:   harbor.New(api, user, pass, r.Insecure),
+	}, nil
+}
+
+// planArtifact is a single tag resolved against the source Harbor.
+type planArtifact struct {
+	Project string
+	Repo    string
+	Tag     string
+	Digest  string
+	Size    int64
+	Chart   bool // Helm chart rather than an image
+
+	Platforms []string           // as in planItem
+	Available []string           // platforms of a multi-arch image on the source
+	Children  []harbor.Reference // child manifests of a multi-arch image
+
+	// Where the tag lands on the destination, after the rule's mapping
+	DstProject string
+	DstRepo    string
+	DstTag     string
+}
+
+// buildPlan resolves which repos (and tag globs) of a project to take, either
+// from a rules file or from the legacy --repo/--tags flags.
+func buildPlan(srcHC *harbor.Client, project, repo string, tags []string, rulesFile string) ([]planItem, error) {
+	var plan []planItem
+
+	if rulesFile != "" {
+		rs, err := rules.Load(rulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("load rules: %w", err)
+		}
+		for _, p := range rs.Projects {
+			if p.Name != project {
+				continue
+			}
+			// discover repos from source Harbor API
+			list, err := srcHC.ListRepos(project)
+			if err != nil {
+				return nil, fmt.Errorf("list repos: %w", err)
+			}
+			for _, r := range list {
+				repo := repoName(r.Name)
+
+				// apply include/exclude
+				if len(p.Includes) > 0 && !globAny(p.Includes, repo) {
+					continue
+				}
+				if len(p.Excludes) > 0 && globAny(p.Excludes, repo) {
+					continue
+				}
+
+				tgs := p.Tags
+				if len(tgs) == 0 {
+					tgs = []string{"*"}
+				}
+				plan = append(plan, planItem{Project: project, Repo: repo, Tags: tgs, Platforms: p.Platforms, Mapping: p.Mapping})
+			}
+		}
+		return plan, nil
+	}
+
+	// legacy --repo / --tags path
+	var repos []string
+	if repo == "" {
+		list, err := srcHC.ListRepos(project)
+		if err != nil {
+			return nil, fmt.Errorf("list repos: %w", err)
+		}
+		for _, r := range list {
+			repos = append(repos, repoName(r.Name))
+		}
+	} else {
+		repos = []string{repo}
+	}
+	tgs := tags
+	if len(tgs) == 0 {
+		tgs = []string{"*"}
+	}
+	for _, r := range repos {
+		plan = append(plan, planItem{Project: project, Repo: r, Tags: tgs})
+	}
+	return plan, nil
+}
+
+// resolvePlan lists the artifacts of every planned repo and keeps the tags
+// matching the item's globs, with their destination names. Charts are left
+// to resolveCharts. Repos that fail to list or map are reported and skipped.
+func resolvePlan(srcHC *harbor.Client, plan []planItem) []planArtifact {
+	var out []planArtifact
+	for _, item := range plan {
+		dstProject, dstRepo, err := item.Mapping.Path(item.Project, item.Repo)
+		if err != nil {
+			color.Red("skip %s/%s: %v", item.Project, item.Repo, err)
+			continue
+		}
+		arts, err := srcHC.ListArtifacts(item.Project, item.Repo)
+		if err != nil {
+			color.Red("skip %s/%s: %v", item.Project, item.Repo, err)
+			continue
+		}
+		for _, a := range arts {
+			if a.IsChart() {
+				continue
+			}
+			for _, tg := range a.Tags {
+				if !globAny(item.Tags, tg.Name) {
+					continue
+				}
+				dstTag, err := item.Mapping.TagOf(item.Project, item.Repo, tg.Name)
+				if err != nil {
+					color.Red("skip %s/%s:%s: %v", item.Project, item.Repo, tg.Name, err)
+					continue
+				}
+				out = append(out, planArtifact{
+					Project: item.Project,
+					Repo:    item.Repo,
+					Tag:     tg.Name,
+					Digest:  a.Digest,
+					Size:    a.Size,
+
+					Platforms: item.Platforms,
+					Available: a.Platforms(),
+					Children:  a.References,
+
+					DstProject: dstProject,
+					DstRepo:    dstRepo,
+					DstTag:     dstTag,
+				})
+			}
+		}
+	}
+	return out
+}
+
+// resolveCharts lists the chart repos of every item's project and keeps the
+// versions matching its name and version globs.
+func resolveCharts(srcHC *harbor.Client, items []chartItem) []planArtifact {
+	var out []planArtifact
+	for _, item := range items {
+		repos, err := srcHC.ListRepos(item.Project)
+		if err != nil {
+			color.Red("skip charts in %s: %v", item.Project, err)
+			continue
+		}
+		for _, r := range repos {
+			name := repoName(r.Name)
+			if item.Name != "" && !globAny([]string{item.Name}, name) {
+				continue
+			}
+			arts, err := srcHC.ListArtifacts(item.Project, name)
+			if err != nil {
+				color.Red("skip %s/%s: %v", item.Project, name, err)
+				continue
+			}
+			for _, a := range arts {
+				if !a.IsChart() {
+					continue
+				}
+				for _, tg := range a.Tags {
+					if !globAny(item.Versions, tg.Name) {
+						continue
+					}
+					out = append(out, planArtifact{
+						Project: item.Project,
+						Repo:    name,
+						Tag:     tg.Name,
+						Digest:  a.Digest,
+						Size:    a.Size,
+						Chart:   true,
+
+						DstProject: item.Project,
+						DstRepo:    name,
+						DstTag:     tg.Name,
+					})
+				}
+			}
+		}
+	}
+	return out
+}
+
+// buildRuleSetPlan expands the entries of a rule set into plans grouped by
+// source registry. Entries without "from" use defaultFrom.
+func buildRuleSetPlan(name string, rs *rules.RuleSet, defaultFrom string,
+	source func(reg string) (*registrySource, error)) (map[string]*sourcePlan, error) {
+
+	plans := map[string]*sourcePlan{}
+	repoCache := map[string][]string{}
+
+	planFor := func(from, project string) (*sourcePlan, *registrySource, error) {
+		if from == "" {
+			from = defaultFrom
+		}
+		if from == "" {
+			return nil, nil, fmt.Errorf("rule set %q: entry for project %q has no \"from\" and no from-registry was given", name, project)
+		}
+		src, err := source(from)
+		if err != nil {
+			return nil, nil, err
+		}
+		if plans[from] == nil {
+			plans[from] = &sourcePlan{}
+		}
+		return plans[from], src, nil
+	}
+
+	for _, e := range rs.Include {
+		if e.Helm != nil {
+			inc := e.Helm
+			sp, _, err := planFor(inc.From, inc.Project)
+			if err != nil {
+				return nil, err
+			}
+			sp.Charts = append(sp.Charts, chartItem{Project: inc.Project, Name: inc.Name, Versions: inc.Versions})
+			continue
+		}
+		inc := e.Image
+		sp, src, err := planFor(inc.From, inc.Project)
+		if err != nil {
+			return nil, err
+		}
+
+		key := src.Name + "\x00" + inc.Project
+		repos, ok := repoCache[key]
+		if !ok {
+			list, err := src.HC.ListRepos(inc.Project)
+			if err != nil {
+				return nil, fmt.Errorf("list repos %s/%s: %w", src.Name, inc.Project, err)
+			}
+			for _, r := range list {
+				repos = append(repos, repoName(r.Name))
+			}
+			repoCache[key] = repos
+		}
+
+		tgs := inc.Tags
+		if len(tgs) == 0 {
+			tgs = []string{"*"}
+		}
+		for _, repo := range repos {
+			if inc.Repo != "" && !globAny([]string{inc.Repo}, repo) {
+				continue
+			}
+			sp.Images = append(sp.Images, planItem{Project: inc.Project, Repo: repo, Tags: tgs, Platforms: inc.Platforms, Mapping: inc.Mapping})
+		}
+	}
+	return plans, nil
+}
+
+// excludedByRuleSet reports whether an artifact from registry "from" matches
+// one of the rule set's excludes of the same kind.
+func excludedByRuleSet(rs *rules.RuleSet, from string, a planArtifact) bool {
+	for _, e := range rs.Exclude {
+		if e.Helm != nil {
+			ex := e.Helm
+			if a.Chart && (ex.From == "" || ex.From == from) && ex.Project == a.Project &&
+				(ex.Name == "" || globAny([]string{ex.Name}, a.Repo)) && globAny(ex.Versions, a.Tag) {
+				return true
+			}
+			continue
+		}
+		ex := e.Image
+		if a.Chart {
+			continue
+		}
+		if ex.From != "" && ex.From != from {
+			continue
+		}
+		if ex.Project != a.Project {
+			continue
+		}
+		if ex.Repo != "" && !globAny([]string{ex.Repo}, a.Repo) {
+			continue
+		}
+		if globAny(ex.Tags, a.Tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// repoName strips the "<project>/" prefix Harbor puts on repository names.
+func repoName(full string) string {
+	parts := strings.SplitN(full, "/", 2)
+	return parts[len(parts)-1]
+}
+
+// apiURL returns the Harbor API base of a registry (api_url, or legacy url).
+func apiURL(r config.Registry) string {
+	if r.APIURL != "" {
+		return r.APIURL
+	}
+	return r.URL
+}
+
+// registryURL returns the host used in image refs (registry_url, or url when
+// Harbor serves both on the same host).
+func registryURL(r config.Registry) string {
+	if r.RegistryURL != "" {
+		return r.RegistryURL
+	}
+	return r.URL
+}
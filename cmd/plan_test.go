@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuildPlanLegacy(t *testing.T) {
+	src := newFakeHarbor(t)
+	src.add("p", "app", imageArt("sha256:a", "v1"))
+	src.add("p", "tools/cli", imageArt("sha256:b", "v1"))
+
+	plan, err := buildPlan(src.client(), "p", "", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var repos []string
+	for _, it := range plan {
+		repos = append(repos, it.Repo)
+		if !slices.Equal(it.Tags, []string{"*"}) {
+			t.Errorf("%s: tags %v, want all", it.Repo, it.Tags)
+		}
+	}
+	slices.Sort(repos)
+	if !slices.Equal(repos, []string{"app", "tools/cli"}) {
+		t.Errorf("repos %v", repos)
+	}
+
+	if _, err := buildPlan(src.client(), "missing", "", nil, ""); err == nil {
+		t.Error("missing project: no error")
+	}
+}
+
+func TestResolvePlanRules(t *testing.T) {
+	src := newFakeHarbor(t)
+	src.add("uruk", "aed/x", indexArt("sha256:x1", []string{"linux/amd64", "linux/arm64"}, "v1", "dev"))
+	src.add("uruk", "aed/x", imageArt("sha256:x2", "v2"))
+	src.add("uruk", "aed/skip", imageArt("sha256:s", "v1"))
+	chart := imageArt("sha256:c", "v1.0")
+	chart.Type = "CHART"
+	src.add("uruk", "aed/x", chart)
+
+	rulesFile := writeFile(t, "rules.yaml", `projects:
+  - name: uruk
+    excludes: ["aed/skip"]
+    tags: ["v*"]
+    platforms: ["linux/amd64"]
+    mapping:
+      project: mirror
+      strip_prefix: aed/
+      tag: "{{.Tag}}-airgap"
+`)
+	plan, err := buildPlan(src.client(), "uruk", "", nil, rulesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arts := resolvePlan(src.client(), plan)
+
+	var got []string
+	for _, a := range arts {
+		got = append(got, a.Project+"/"+a.Repo+":"+a.Tag+" -> "+a.DstProject+"/"+a.DstRepo+":"+a.DstTag)
+		if !slices.Equal(a.Platforms, []string{"linux/amd64"}) {
+			t.Errorf("%s: platforms %v", a.Tag, a.Platforms)
+		}
+	}
+	slices.Sort(got)
+	want := []string{
+		"uruk/aed/x:v1 -> mirror/x:v1-airgap",
+		"uruk/aed/x:v2 -> mirror/x:v2-airgap",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+	for _, a := range arts {
+		if a.Tag == "v1" && (len(a.Children) != 2 || !slices.Equal(a.Available, []string{"linux/amd64", "linux/arm64"})) {
+			t.Errorf("v1: children %v, available %v", a.Children, a.Available)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/executor"
+	"github.com/hakantongur/harair/internal/harbor"
+	"github.com/hakantongur/harair/internal/registry"
+)
+
+// platformsAll selects every platform of a multi-arch image.
+const platformsAll = "all"
+
+// copyPlatforms returns the platforms to copy for an artifact: the rule's
+// setting when it has one, else the command's --platforms. nil means all.
+func copyPlatforms(rule, flag []string) ([]string, error) {
+	list := rule
+	if len(list) == 0 {
+		list = flag
+	}
+	var out []string
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if strings.EqualFold(s, platformsAll) {
+			return nil, nil
+		}
+		if _, err := registry.ParsePlatform(s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// checkPlatformEngine fails a plan with platform subsets that the configured
+// copy engine can't honour, before anything is written or copied.
+func checkPlatformEngine(cfg *config.Config, platforms []string) error {
+	if platforms != nil && !executor.IsNative(cfg.SkopeoPath) {
+		return fmt.Errorf("platforms %s: %w", strings.Join(platforms, ","), executor.ErrPlatforms)
+	}
+	return nil
+}
+
+// describePlatforms says which platforms of a go across with platforms, for
+// dry-run output. It is empty for single-platform images.
+func describePlatforms(a planArtifact, platforms []string) string {
+	if len(a.Available) == 0 {
+		return ""
+	}
+	if platforms == nil {
+		return fmt.Sprintf(" [platforms: all of %s]", strings.Join(a.Available, ","))
+	}
+	want, _ := registry.ParsePlatforms(platforms)
+	var picked []string
+	for _, s := range a.Available {
+		p, err := registry.ParsePlatform(s)
+		if err != nil {
+			continue
+		}
+		for _, w := range want {
+			if w.Matches(&p) {
+				picked = append(picked, s)
+				break
+			}
+		}
+	}
+	if len(picked) == 0 {
+		return fmt.Sprintf(" [platforms: none of %s match %s]", strings.Join(a.Available, ","), strings.Join(platforms, ","))
+	}
+	return fmt.Sprintf(" [platforms: %s of %s]", strings.Join(picked, ","), strings.Join(a.Available, ","))
+}
+
+// platformNote marks a task limited to some platforms, for dry-run output.
+func platformNote(platforms []string) string {
+	if len(platforms) == 0 {
+		return ""
+	}
+	return " [platforms: " + strings.Join(platforms, ",") + "]"
+}
+
+// platformChildren returns the sorted child digests of a that a copy limited
+// to platforms keeps, for comparing with the destination; nil when the whole
+// artifact is copied (single-platform images, or all platforms).
+func platformChildren(a planArtifact, platforms []string) []string {
+	if platforms == nil || len(a.Children) == 0 {
+		return nil
+	}
+	want, err := registry.ParsePlatforms(platforms)
+	if err != nil {
+		return nil
+	}
+	var keep []harbor.Reference
+	for _, r := range a.Children {
+		if r.Platform == nil {
+			continue
+		}
+		p := registry.Platform{OS: r.Platform.OS, Architecture: r.Platform.Architecture, Variant: r.Platform.Variant}
+		for _, w := range want {
+			if w.Matches(&p) {
+				keep = append(keep, r)
+				break
+			}
+		}
+	}
+	if len(keep) == 0 || len(keep) == len(a.Children) {
+		return nil // the copy fails, or the index is copied as it is
+	}
+	return childDigests(keep)
+}
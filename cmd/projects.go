@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/harbor"
+	"github.com/hakantongur/harair/internal/report"
+)
+
+// kindCreateProject is the report kind of a project created on the destination.
+const kindCreateProject = "create-project"
+
+// projectSettings are the project metadata keys --create-projects copies.
+var projectSettings = []string{"public", "auto_scan", "enable_content_trust", "enable_content_trust_cosign"}
+
+// projectOrigin is the source project a destination project is filled from.
+type projectOrigin struct {
+	From    string // source registry
+	Project string // its name there; a rule's mapping may rename it
+}
+
+// projectCreate is a destination project to create like its source project.
+type projectCreate struct {
+	projectOrigin
+	Req harbor.ProjectReq
+}
+
+// planProjects returns the projects of names missing on the destination,
+// with the settings and quota of their source project (from maps each name
+// to its origin). A source quota that cannot be read (it needs admin rights)
+// leaves the destination's default.
+func planProjects(names []string, from map[string]projectOrigin, sources map[string]*registrySource,
+	dstHC *harbor.Client) ([]projectCreate, error) {
+
+	var out []projectCreate
+	for _, name := range names {
+		_, err := dstHC.GetProject(name)
+		if err == nil {
+			continue
+		}
+		if !harbor.IsNotFound(err) {
+			return nil, fmt.Errorf("destination project %s: %w", name, err)
+		}
+
+		o := from[name]
+		src := sources[o.From]
+		sp, err := src.HC.GetProject(o.Project)
+		if err != nil {
+			return nil, fmt.Errorf("source project %s on %s: %w", o.Project, o.From, err)
+		}
+		req := harbor.ProjectReq{Name: name, Metadata: map[string]string{}}
+		for _, k := range projectSettings {
+			if v, ok := sp.Metadata[k]; ok {
+				req.Metadata[k] = v
+			}
+		}
+		if q, err := src.HC.ProjectQuota(sp.ProjectID); err != nil {
+			color.Yellow("Quota of project %s on %s not readable, using the destination default: %v", o.Project, o.From, err)
+		} else if s, ok := q.Hard["storage"]; ok {
+			req.StorageLimit = &s
+		}
+		out = append(out, projectCreate{projectOrigin: o, Req: req})
+	}
+	return out, nil
+}
+
+// describeProject summarizes the settings a project is created with.
+func describeProject(req harbor.ProjectReq) string {
+	parts := []string{"private"}
+	if req.Metadata["public"] == "true" {
+		parts[0] = "public"
+	}
+	if req.StorageLimit != nil {
+		if *req.StorageLimit < 0 {
+			parts = append(parts, "quota unlimited")
+		} else {
+			parts = append(parts, "quota "+humanSize(*req.StorageLimit))
+		}
+	}
+	if req.Metadata["auto_scan"] == "true" {
+		parts = append(parts, "auto-scan")
+	}
+	if req.Metadata["enable_content_trust"] == "true" {
+		parts = append(parts, "content-trust")
+	}
+	if req.Metadata["enable_content_trust_cosign"] == "true" {
+		parts = append(parts, "cosign")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// plannedProjects prints and records the projects a dry-run would create.
+func plannedProjects(creates []projectCreate, toReg string, rep *taskReport) {
+	for _, p := range creates {
+		color.Yellow("[dry-run] create project %s on %s like %s/%s (%s)", p.Req.Name, toReg, p.From, p.Project, describeProject(p.Req))
+		rep.add(report.Task{Kind: kindCreateProject, Destination: toReg + "/" + p.Req.Name, Outcome: report.Planned})
+	}
+}
+
+// createProjects creates the projects on the destination. A project created
+// meanwhile (409) is left as it is; settings Harbor dropped on create are set
+// again.
+func createProjects(dstHC *harbor.Client, creates []projectCreate, toReg string, rep *taskReport) error {
+	for _, p := range creates {
+		start := time.Now()
+		err := createProject(dstHC, p.Req)
+		rt := report.Task{Kind: kindCreateProject, Destination: toReg + "/" + p.Req.Name,
+			Duration: time.Since(start), Outcome: copySucceeded.String()}
+		if err != nil {
+			rt.Outcome, rt.Error = copyFailed.String(), err.Error()
+			rep.add(rt)
+			return fmt.Errorf("create project %s on %s: %w", p.Req.Name, toReg, err)
+		}
+		rep.add(rt)
+		color.Green("Created project %s on %s (%s)", p.Req.Name, toReg, describeProject(p.Req))
+	}
+	return nil
+}
+
+func createProject(hc *harbor.Client, req harbor.ProjectReq) error {
+	if err := hc.CreateProject(req); err != nil {
+		var se *harbor.StatusError
+		if errors.As(err, &se) && se.Code == http.StatusConflict {
+			return nil
+		}
+		return err
+	}
+	got, err := hc.GetProject(req.Name)
+	if err != nil {
+		return err
+	}
+	fix := harbor.ProjectReq{Metadata: map[string]string{}}
+	for k, v := range req.Metadata {
+		if got.Metadata[k] != v {
+			fix.Metadata[k] = v
+		}
+	}
+	if len(fix.Metadata) == 0 {
+		return nil
+	}
+	return hc.UpdateProject(req.Name, fix)
+}
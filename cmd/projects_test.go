@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPlanProjects(t *testing.T) {
+	src, dst := newFakeHarbor(t), newFakeHarbor(t)
+	src.project("uruk", map[string]string{"public": "true", "auto_scan": "true", "retention_id": "7"}, 100, 5<<30)
+	src.project("core", map[string]string{"public": "false"}, 0, -1)
+	dst.project("core", nil, 0, -1) // already there
+	sources := map[string]*registrySource{"src": {Name: "src", HC: src.client()}}
+	from := map[string]projectOrigin{"mirror-uruk": {From: "src", Project: "uruk"}, "core": {From: "src", Project: "core"}}
+
+	creates, err := planProjects([]string{"mirror-uruk", "core"}, from, sources, dst.client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(creates) != 1 {
+		t.Fatalf("creates %+v, want mirror-uruk only", creates)
+	}
+	req := creates[0].Req
+	if req.Name != "mirror-uruk" || creates[0].Project != "uruk" ||
+		!maps.Equal(req.Metadata, map[string]string{"public": "true", "auto_scan": "true"}) ||
+		req.StorageLimit == nil || *req.StorageLimit != 5<<30 {
+		t.Errorf("create %+v", req)
+	}
+	if got := describeProject(req); got != "public, quota 5.0 GiB, auto-scan" {
+		t.Errorf("describeProject = %q", got)
+	}
+
+	// a quota that can't be read leaves the destination's default
+	src.QuotaStatus = http.StatusForbidden
+	creates, err = planProjects([]string{"mirror-uruk"}, from, sources, dst.client())
+	if err != nil || len(creates) != 1 || creates[0].Req.StorageLimit != nil {
+		t.Errorf("no quota rights: %+v %v", creates, err)
+	}
+
+	dst.Status = http.StatusInternalServerError
+	if _, err := planProjects([]string{"mirror-uruk"}, from, sources, dst.client()); err == nil {
+		t.Error("destination error: no error")
+	}
+}
+
+func TestCreateProjects(t *testing.T) {
+	src, dst := newFakeHarbor(t), newFakeHarbor(t)
+	src.project("uruk", map[string]string{"public": "true", "auto_scan": "true", "enable_content_trust": "true"}, 0, 1<<30)
+	dst.Drop = []string{"auto_scan", "enable_content_trust"} // as some Harbor versions do on create
+	sources := map[string]*registrySource{"src": {Name: "src", HC: src.client()}}
+	creates, err := planProjects([]string{"uruk"}, map[string]projectOrigin{"uruk": {From: "src", Project: "uruk"}}, sources, dst.client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// dry-run: listed, nothing created
+	plannedProjects(creates, "dst", nil)
+	if calls := dst.Calls(); len(calls) != 0 {
+		t.Errorf("dry-run changed the destination: %v", calls)
+	}
+
+	if err := createProjects(dst.client(), creates, "dst", nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls := dst.Calls(); !slices.Equal(calls, []string{"POST projects", "PUT projects/uruk"}) {
+		t.Errorf("calls %v, want a create and an update for the dropped settings", calls)
+	}
+	dst.mu.Lock()
+	md, quota := dst.meta["uruk"].Metadata, dst.quotas["uruk"].Hard["storage"]
+	dst.mu.Unlock()
+	if !maps.Equal(md, creates[0].Req.Metadata) || quota != 1<<30 {
+		t.Errorf("created with %v, quota %d", md, quota)
+	}
+
+	// created meanwhile: 409 is fine, and the project is left as it is
+	if err := createProjects(dst.client(), creates, "dst", nil); err != nil {
+		t.Errorf("409: %v", err)
+	}
+	if calls := dst.Calls(); len(calls) != 3 || calls[2] != "POST projects" {
+		t.Errorf("calls after 409: %v", calls)
+	}
+
+	dst.Status = http.StatusForbidden
+	if err := createProjects(dst.client(), creates, "dst", nil); err == nil || !strings.Contains(err.Error(), "create project uruk on dst") {
+		t.Errorf("forbidden: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/journal"
+	"github.com/hakantongur/harair/internal/report"
+)
+
+// taskReport collects report tasks from planning and from the copy workers.
+// A nil *taskReport (no --report) ignores everything.
+type taskReport struct {
+	specs []report.Spec
+
+	mu    sync.Mutex
+	tasks []report.Task
+}
+
+// newTaskReport parses --report values; it returns nil when there are none.
+func newTaskReport(values []string) (*taskReport, error) {
+	specs, err := report.ParseSpecs(values)
+	if err != nil || len(specs) == 0 {
+		return nil, err
+	}
+	return &taskReport{specs: specs}, nil
+}
+
+func (r *taskReport) add(t report.Task) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks = append(r.tasks, t)
+}
+
+// planned records a task that was not executed (dry-run or up-to-date).
+func (r *taskReport) planned(t journal.Task, outcome string) {
+	r.add(reportTask(t, outcome))
+}
+
+// executed records the result of running t.
+func (r *taskReport) executed(t journal.Task, res copyResult) {
+	rt := reportTask(t, res.outcome.String())
+	rt.Source = res.task.srcRef
+	rt.Duration = res.duration
+	rt.Attempts = res.attempts
+	if res.err != nil {
+		rt.Class = string(res.errClass())
+		rt.Error = res.err.Error()
+	}
+	r.add(rt)
+}
+
+// verified records verification results on the copy tasks they checked.
+func (r *taskReport) verified(results []verifyResult) {
+	if r == nil {
+		return
+	}
+	byDst := map[string]verifyResult{}
+	for _, v := range results {
+		byDst[v.task.DstRef] = v
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, t := range r.tasks {
+		v, ok := byDst[t.Destination]
+		if !ok || t.Kind != journal.KindCopy {
+			continue
+		}
+		r.tasks[i].Verify = v.status
+		if v.v != nil {
+			r.tasks[i].DestDigest = v.v.Dest.Digest
+			r.tasks[i].Blobs = v.v.Blobs
+		}
+		if v.err != nil && r.tasks[i].Error == "" {
+			r.tasks[i].Error = v.err.Error()
+		}
+	}
+}
+
+// write writes the finished report in every requested format.
+func (r *taskReport) write(rep report.Report) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	rep.Tasks = r.tasks
+	r.mu.Unlock()
+	if err := report.Write(r.specs, &rep); err != nil {
+		color.Red("report: %v", err)
+		return
+	}
+	for _, s := range r.specs {
+		color.Cyan("Report (%s): %s", s.Format, s.Path)
+	}
+}
+
+func reportTask(t journal.Task, outcome string) report.Task {
+	return report.Task{
+		Kind:        t.Kind,
+		Source:      t.SrcRef,
+		Destination: t.DstRef,
+		Digest:      t.Digest,
+		Size:        t.Size,
+		Platforms:   t.Platforms,
+		Outcome:     outcome,
+	}
+}
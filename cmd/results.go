@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/retry"
+)
+
+// Exit codes for commands that run copies (and diff, verify).
+const (
+	exitOK             = 0
+	exitError          = 1 // usage, config or planning error
+	exitPartialFailure = 2 // some copies failed, others succeeded
+	exitTotalFailure   = 3 // every attempted copy failed
+	exitDrift          = 4 // diff found differences
+	exitVerifyFailed   = 5 // verify found a mismatch or missing content
+)
+
+// exitCodeError carries a specific process exit code out of a command.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+type copyOutcome int
+
+const (
+	copySucceeded copyOutcome = iota
+	copySkipped               // missing on source
+	copyFailed
+)
+
+func (o copyOutcome) String() string {
+	switch o {
+	case copySkipped:
+		return "skipped"
+	case copyFailed:
+		return "failed"
+	default:
+		return "succeeded"
+	}
+}
+
+// copyResult is what happened to one copy task.
+type copyResult struct {
+	task     copyTask
+	outcome  copyOutcome
+	err      error
+	class    retry.Class // of err; set by runCopies, else derived from err
+	attempts int
+	duration time.Duration
+}
+
+// errClass returns the failure class of r.
+func (r copyResult) errClass() retry.Class {
+	if r.class == retry.None && r.err != nil {
+		return retry.Classify(r.err)
+	}
+	return r.class
+}
+
+// summarizeCopies prints the final tally and returns an error carrying
+// exitPartialFailure or exitTotalFailure when any copy failed.
+func summarizeCopies(results []copyResult) error {
+	var ok, skipped, failed int
+	for _, r := range results {
+		switch r.outcome {
+		case copySucceeded:
+			ok++
+		case copySkipped:
+			skipped++
+		case copyFailed:
+			failed++
+		}
+	}
+
+	fmt.Printf("Summary: %s, %s, %s\n",
+		color.GreenString("%d succeeded", ok),
+		color.YellowString("%d skipped (missing on source)", skipped),
+		color.RedString("%d failed", failed))
+
+	if failed == 0 {
+		return nil
+	}
+	byClass := map[retry.Class]int{}
+	for _, r := range results {
+		if r.outcome == copyFailed {
+			byClass[r.errClass()]++
+			color.Red("  FAILED [%s] %s -> %s", r.errClass(), r.task.srcRef, r.task.dstRef)
+		}
+	}
+	var classes []string
+	for _, c := range []retry.Class{retry.Network, retry.Server, retry.RateLimit, retry.Auth, retry.Quota, retry.NotFound, retry.Other} {
+		if byClass[c] > 0 {
+			classes = append(classes, fmt.Sprintf("%s=%d", c, byClass[c]))
+		}
+	}
+	color.Red("Failures by class: %s", strings.Join(classes, ", "))
+	if ok == 0 {
+		return &exitCodeError{code: exitTotalFailure, err: fmt.Errorf("all %d copy operation(s) failed", failed)}
+	}
+	return &exitCodeError{code: exitPartialFailure, err: fmt.Errorf("%d of %d copy operation(s) failed", failed, ok+failed)}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/executor"
+	"github.com/hakantongur/harair/internal/harbor"
+	"github.com/hakantongur/harair/internal/journal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	retryDryRun        bool
+	retryDockerNetwork string
+	retryConcurrency   int
+)
+
+var retryCmd = &cobra.Command{
+	Use:   "retry <failed-file>",
+	Short: "Re-run the failed tasks of a sync (defaults to --dry-run)",
+	Long: `Re-run exactly the tasks listed in a failed-task file written by sync
+(--failed-file, by default next to the sync journal). Like sync, it only lists
+the tasks unless --dry-run=false is given.
+
+Credentials for each task are resolved from its source and destination
+registry names, as in sync. Tasks that fail again are written back to the
+file, so retry can be repeated until it is empty. Exit codes are those of sync.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+		f, err := journal.ReadFailed(path)
+		if err != nil {
+			return err
+		}
+		if len(f.Tasks) == 0 {
+			color.Green("No failed tasks in %s.", path)
+			return nil
+		}
+
+		// group by destination registry, in file order
+		var order []string
+		byDest := map[string][]journal.Task{}
+		for _, t := range f.Tasks {
+			if _, ok := byDest[t.To]; !ok {
+				order = append(order, t.To)
+			}
+			byDest[t.To] = append(byDest[t.To], t.Task)
+		}
+
+		if retryDryRun {
+			for _, ft := range f.Tasks {
+				color.Yellow("[dry-run] (%s) %s %s -> %s", ft.Class, ft.Kind, ft.SrcRef, ft.DstRef)
+			}
+			return nil
+		}
+
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+		exec, err := newExecutor(cfg, retryDockerNetwork, nil)
+		if err != nil {
+			return err
+		}
+		defer exec.Close()
+
+		var mu sync.Mutex
+		var results []copyResult
+		var still []journal.FailedTask
+		for _, to := range order {
+			record := func(t journal.Task, r copyResult) {
+				if r.outcome != copyFailed {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				still = append(still, journal.FailedTask{Task: t, To: to, Class: string(r.errClass()), Error: r.err.Error()})
+			}
+
+			tr, ok := cfg.Registries[to]
+			if !ok {
+				err := fmt.Errorf("registry %q not in %s", to, cfgPath)
+				color.Red("%v", err)
+				for _, t := range byDest[to] {
+					r := copyResult{task: copyTask{srcRef: t.SrcRef, dstRef: t.DstRef}, outcome: copyFailed, err: err}
+					record(t, r)
+					results = append(results, r)
+				}
+				continue
+			}
+			tu, tp, _ := getCreds(cfg, to)
+			dstHC := harbor.New(apiURL(tr), tu, tp, tr.Insecure)
+			color.Cyan("Retrying %d task(s) -> %s", len(byDest[to]), to)
+			results = append(results, runTasks(byDest[to], cfg, exec, retryConcurrency, dstHC,
+				executor.Endpoint{User: tu, Pass: tp, Insecure: tr.Insecure}, record)...)
+		}
+
+		sort.SliceStable(still, func(a, b int) bool { return still[a].ID < still[b].ID })
+		if err := journal.WriteFailed(path, still); err != nil {
+			color.Red("write failed-task file: %v", err)
+		} else if len(still) > 0 {
+			color.Yellow("%d task(s) still failing, written back to %s", len(still), path)
+		}
+		if err := summarizeCopies(results); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(retryCmd)
+	retryCmd.Flags().BoolVar(&retryDryRun, "dry-run", true, "List the tasks that would be retried, do not execute")
+	retryCmd.Flags().StringVar(&retryDockerNetwork, "docker-network", "", "Docker network for skopeo")
+	retryCmd.Flags().IntVar(&retryConcurrency, "concurrency", 2, "Number of parallel copy operations")
+}
@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/retry"
+	"github.com/hakantongur/harair/internal/shell"
 	"github.com/spf13/cobra"
 )
 
@@ -12,28 +16,62 @@ var (
 	cfgPath   string
 	rulesPath string
 	verbose   bool
+
+	credsFlags []string
+	credsStdin bool
+
+	retries      int
+	retryBackoff time.Duration
 )
 
+// maxRetryBackoff caps the exponential backoff between copy retries.
+const maxRetryBackoff = time.Minute
+
 var rootCmd = &cobra.Command{
 	Use:   "harair",
 	Short: "Harbor Air-Gap CLI",
 	Long:  `harair: Mirror, export, and import Harbor images & Helm charts across air-gapped networks.`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		if verbose {
-			color.New(color.FgCyan).Println("[harair] verbose logging enabled")
+			color.New(color.FgCyan).Fprintln(os.Stderr, "[harair] verbose logging enabled")
 		}
+		shell.Verbose = verbose
+		var err error
+		flagCreds, err = parseCredsFlags(credsFlags, credsStdin, os.Stdin)
+		return err
 	},
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, err) // keep stdout clean for -o json/yaml
+		os.Exit(exitCode(err))
 	}
 }
 
+// exitCode is the process exit code for a command's error.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var ee *exitCodeError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	return exitError
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgPath, "config", "config.yaml", "Path to config.yaml")
 	rootCmd.PersistentFlags().StringVar(&rulesPath, "rules", "rules.yaml", "Path to rules.yaml")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	rootCmd.PersistentFlags().StringArrayVar(&credsFlags, "creds", nil, "Username as <registry>=<user> (repeatable; overrides env, auth store, docker config and config.yaml). The password comes from --creds-stdin or HARAIR_<REGISTRY>_PASSWORD, never argv")
+	rootCmd.PersistentFlags().BoolVar(&credsStdin, "creds-stdin", false, "Read the --creds passwords from stdin, one line each in --creds order")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 3, "Retries per copy on transient errors (network, 5xx, 429)")
+	rootCmd.PersistentFlags().DurationVar(&retryBackoff, "retry-backoff", 2*time.Second, "Initial delay between retries; doubles each retry, with jitter")
+}
+
+// retryPolicy is the copy retry policy set by --retries and --retry-backoff.
+func retryPolicy() retry.Policy {
+	return retry.Policy{Attempts: retries + 1, Base: retryBackoff, Max: maxRetryBackoff}
 }
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// runCmd runs harair with args against cfg and returns its exit code and
+// stdout. Flags are back at their defaults afterwards.
+func runCmd(t *testing.T, cfg *config.Config, args ...string) (int, string) {
+	t.Helper()
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args = append([]string{"--config", writeFile(t, "config.yaml", string(b))}, args...)
+
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	oldStdout, oldColor := os.Stdout, color.Output
+	os.Stdout, color.Output = out, out
+	defer func() { os.Stdout, color.Output = oldStdout, oldColor }()
+	defer resetFlags(rootCmd)
+
+	var stderr strings.Builder // cobra's error and usage
+	rootCmd.SetArgs(args)
+	rootCmd.SetOut(&stderr)
+	rootCmd.SetErr(&stderr)
+	defer func() { rootCmd.SetOut(nil); rootCmd.SetErr(nil) }()
+	err = rootCmd.Execute()
+	if err != nil {
+		t.Logf("harair %s: %v", strings.Join(args, " "), err)
+	}
+	b, _ = os.ReadFile(out.Name())
+	return exitCode(err), string(b)
+}
+
+// setStdin makes s the process's stdin for the rest of the test.
+func setStdin(t *testing.T, s string) {
+	t.Helper()
+	f, err := os.Open(writeFile(t, "stdin", s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() { os.Stdin = old; f.Close() })
+}
+
+// resetFlags puts every flag of c and its subcommands back to its default,
+// as the flag variables outlive a single Execute.
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			var def []string
+			if s := strings.Trim(f.DefValue, "[]"); s != "" {
+				def = strings.Split(s, ",")
+			}
+			sv.Replace(def)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func TestSyncPlatformsNeedNative(t *testing.T) {
+	src, dst := newFakeHarbor(t), newFakeHarbor(t)
+	src.add("p", "app", indexArt("sha256:idx", []string{"linux/amd64", "linux/arm64"}, "v1"))
+	cfg := testConfig(t, map[string]*fakeHarbor{"src": src, "dst": dst})
+	cfg.SkopeoPath = "skopeo"
+	journals := filepath.Join(os.Getenv("HOME"), ".harair", "journal")
+
+	for _, dry := range []string{"true", "false"} {
+		code, _ := runCmd(t, cfg, "sync", "src", "dst", "--project", "p", "--platforms", "linux/amd64", "--dry-run="+dry)
+		if code != exitError {
+			t.Errorf("--dry-run=%s: exit %d, want %d", dry, code, exitError)
+		}
+		if _, err := os.Stat(journals); !os.IsNotExist(err) {
+			t.Errorf("--dry-run=%s: journal written (%v)", dry, err)
+		}
+	}
+
+	cfg.SkopeoPath = "native"
+	if code, out := runCmd(t, cfg, "sync", "src", "dst", "--project", "p", "--platforms", "linux/amd64"); code != exitOK || !strings.Contains(out, "platforms: linux/amd64 of") {
+		t.Errorf("native dry-run: exit %d\n%s", code, out)
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -8,40 +9,124 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/executor"
 	"github.com/hakantongur/harair/internal/harbor"
+	"github.com/hakantongur/harair/internal/journal"
+	"github.com/hakantongur/harair/internal/report"
+	"github.com/hakantongur/harair/internal/retry"
 	"github.com/hakantongur/harair/internal/rules"
-	"github.com/hakantongur/harair/internal/shell"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 )
 
 // ----- flags -----
 var (
-	syncProject       string
-	syncRepo          string
-	syncTags          []string
-	dryRun            bool
-	syncDockerNetwork string
-	syncRulesPath     string
-	maxConcurrent     int
+	syncProject        string
+	syncRepo           string
+	syncTags           []string
+	dryRun             bool
+	syncDockerNetwork  string
+	syncRulesPath      string
+	syncRuleSet        string
+	syncCharts         []string
+	syncChartVersions  []string
+	syncChartRepo      bool
+	syncForce          bool
+	syncJournal        string
+	syncResume         string
+	syncFailedFile     string
+	syncReports        []string
+	syncVerify         bool
+	syncVerifyBlobs    bool
+	syncPlatforms      []string
+	syncMode           string
+	syncMaxDeletes     int
+	syncYes            bool
+	syncCreateProjects bool
+	maxConcurrent      int
 )
 
 // ----- types -----
 type copyTask struct {
-	srcRef string
-	dstRef string
+	srcRef    string
+	dstRef    string
+	platforms []string // empty => all
 }
 
 // ----- command -----
 var syncCmd = &cobra.Command{
 	Use:   "sync [from-registry] [to-registry]",
 	Short: "Copy images between registries (defaults to --dry-run)",
-	Args:  cobra.ExactArgs(2),
+	Long: `Copy images between registries (defaults to --dry-run).
+
+Exit codes: 0 when every copy succeeded (or was skipped as missing on the
+source), 2 when some copies failed, 3 when all of them failed, 1 on any other
+error.
+
+With --rule-set, the source registry of each entry comes from its "from" key,
+so only the destination is required: sync --rule-set core-images harbor2.
+A from-registry given on the command line is used for entries without "from".
+
+A rule's mapping may rename the destination project, repo and tag (see
+rules.yaml); the destination refs in the dry-run show the result.
+
+Copies pull the digest seen while planning (repo@sha256:...) and apply the
+tag on the destination, so a tag that moves on the source mid-run does not
+change what is mirrored.
+
+Every run with copies to make writes a journal of its planned tasks and
+their states (--journal).
+If a run is interrupted, sync --resume <journal> --dry-run=false continues
+the unfinished and failed tasks with the same digests.
+
+--create-projects checks the destination projects first and creates the
+missing ones like their source project: public/private, storage quota,
+auto-scan and content trust.
+
+--mode mirror also deletes destination tags within the plan's scope (same
+project and repo, matching tag globs, not excluded) that no longer exist on
+the source, and whole artifacts when none of their tags remain. Deletions are
+always listed first, are capped by --max-deletes, need confirmation (or
+--yes), and run after the copies.
+
+--verify checks every copied tag afterwards (see harair verify); any mismatch
+or missing content exits 5 unless copies already failed.`,
+	Args: cobra.RangeArgs(0, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		fromReg, toReg := args[0], args[1]
+		started := time.Now().UTC()
+		rep, err := newTaskReport(syncReports)
+		if err != nil {
+			return err
+		}
+
+		if syncResume != "" {
+			if len(args) > 0 {
+				return fmt.Errorf("--resume takes no registry arguments (they are in the journal)")
+			}
+			if err := resumeSync(syncResume, rep); err != nil {
+				if _, ok := err.(*exitCodeError); ok {
+					cmd.SilenceUsage = true
+				}
+				return err
+			}
+			return nil
+		}
+
+		var fromReg, toReg string
+		switch {
+		case len(args) == 2:
+			fromReg, toReg = args[0], args[1]
+		case syncRuleSet != "":
+			toReg = args[0]
+		default:
+			return fmt.Errorf("accepts 2 arg(s) [from-registry] [to-registry] (or --resume <journal>), received %d", len(args))
+		}
 
-		if syncProject == "" {
-			color.Red("Please provide --project")
+		if syncMode != modeCopy && syncMode != modeMirror {
+			return fmt.Errorf("invalid --mode %q: want copy or mirror", syncMode)
+		}
+		if syncProject == "" && syncRuleSet == "" {
+			color.Red("Please provide --project or --rule-set")
 			return nil
 		}
 
@@ -49,150 +134,262 @@ var syncCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		fr, ok := cfg.Registries[fromReg]
-		if !ok {
-			return fmt.Errorf("registry %q not in %s", fromReg, cfgPath)
-		}
 		tr, ok := cfg.Registries[toReg]
 		if !ok {
 			return fmt.Errorf("registry %q not in %s", toReg, cfgPath)
 		}
-
-		fu, fp, _ := getCreds(cfg, fromReg) // optional (mocks may not need)
 		tu, tp, _ := getCreds(cfg, toReg)
 
-		// Harbor API bases (discovery)
-		srcAPI := fr.APIURL
-		if srcAPI == "" {
-			srcAPI = fr.URL // legacy fallback
-		}
-		if strings.TrimSpace(srcAPI) == "" {
-			return fmt.Errorf("registry %q: api_url/url is empty in config.yaml", fromReg)
+		// dst API: what is already there, and the classic chart repository upload
+		dstHC := harbor.New(apiURL(tr), tu, tp, tr.Insecure)
+		chartRepo := syncChartRepo
+		if chartRepo {
+			if chartRepo, err = checkChartRepo(dstHC, toReg); err != nil {
+				return err
+			}
 		}
-		srcHC := harbor.New(srcAPI, fu, fp, fr.Insecure)
 
-		// (currently we don't need dst API for discovery, keep for future)
-		// dstAPI := tr.APIURL
-		// if dstAPI == "" { dstAPI = tr.URL }
-
-		// Build a plan: list of {repo, tag globs}
-		type planItem struct {
-			Repo string
-			Tags []string
+		// Source registries, in the order they are first used
+		var order []string
+		sources := map[string]*registrySource{}
+		source := func(name string) (*registrySource, error) {
+			if s, ok := sources[name]; ok {
+				return s, nil
+			}
+			s, err := newRegistrySource(cfg, name)
+			if err != nil {
+				return nil, err
+			}
+			sources[name] = s
+			order = append(order, name)
+			return s, nil
 		}
-		var plan []planItem
 
-		if syncRulesPath != "" {
-			rs, err := rules.Load(syncRulesPath)
+		// Build a plan per source: images {project, repo, tag globs} and charts
+		plans := map[string]*sourcePlan{}
+		var rs *rules.RuleSet
+		if syncRuleSet != "" {
+			path := syncRulesPath
+			if path == "" {
+				path = rulesPath
+			}
+			f, err := rules.Load(path)
 			if err != nil {
 				return fmt.Errorf("load rules: %w", err)
 			}
-			for _, p := range rs.Projects {
-				if p.Name != syncProject {
+			if rs, err = f.RuleSet(syncRuleSet); err != nil {
+				return err
+			}
+			if plans, err = buildRuleSetPlan(syncRuleSet, rs, fromReg, source); err != nil {
+				return err
+			}
+			if len(plans) == 0 {
+				color.Yellow("No repos matched by rule set %q.", syncRuleSet)
+				return nil
+			}
+		} else {
+			src, err := source(fromReg)
+			if err != nil {
+				return err
+			}
+			plan, err := buildPlan(src.HC, syncProject, syncRepo, syncTags, syncRulesPath)
+			if err != nil {
+				return err
+			}
+			if syncRulesPath != "" && len(plan) == 0 {
+				color.Yellow("No repos matched by %s for project %q.", syncRulesPath, syncProject)
+				if dryRun {
+					return nil
+				}
+			}
+			sp := &sourcePlan{Images: plan}
+			for _, name := range syncCharts {
+				sp.Charts = append(sp.Charts, chartItem{Project: syncProject, Name: name, Versions: syncChartVersions})
+			}
+			plans[fromReg] = sp
+		}
+
+		// Resolve registry endpoints for copy
+		dstReg := registryURL(tr)
+		dstIdx := newDestIndex(dstHC)
+
+		// Build the copy tasks of every source, then run them from a journal
+		var planned []journal.Task
+		var projects []string                     // destination projects getting copies
+		projectFrom := map[string]projectOrigin{} // and the source project they come from
+		dstFrom := map[string]string{}            // destination ref -> source ref, to catch clashes
+		for _, name := range order {
+			src := sources[name]
+			srcReg := registryURL(src.Reg)
+
+			// Charts travel as OCI artifacts through the same copy path as images
+			arts := resolvePlan(src.HC, plans[name].Images)
+			arts = append(arts, resolveCharts(src.HC, plans[name].Charts)...)
+
+			counts := map[taskState]int{}
+			for _, a := range arts {
+				if rs != nil && excludedByRuleSet(rs, name, a) {
 					continue
 				}
-				// discover repos from source Harbor API
-				list, err := srcHC.ListRepos(syncProject)
+				// pull the digest seen now; the tag is applied on the destination
+				srcRef := pinRef(fmt.Sprintf("docker://%s/%s/%s:%s",
+					trimScheme(srcReg), a.Project, a.Repo, a.Tag), a.Digest)
+				// the rule's mapping may rename the destination
+				dstRef := fmt.Sprintf("docker://%s/%s/%s:%s",
+					trimScheme(dstReg), a.DstProject, a.DstRepo, a.DstTag)
+				if prev, ok := dstFrom[dstRef]; ok {
+					return fmt.Errorf("%s and %s would both be copied to %s; fix the mapping in the rules", prev, srcRef, dstRef)
+				}
+				dstFrom[dstRef] = srcRef
+				platforms, err := copyPlatforms(a.Platforms, syncPlatforms)
 				if err != nil {
-					return fmt.Errorf("list repos: %w", err)
+					return err
+				}
+				if a.Chart {
+					platforms = nil // charts are single OCI artifacts
+				}
+				if err := checkPlatformEngine(cfg, platforms); err != nil {
+					return err
+				}
+
+				ts := []journal.Task{{Kind: journal.KindCopy, From: name,
+					Project: a.Project, Repo: a.Repo, Tag: a.Tag, Digest: a.Digest, Size: a.Size,
+					SrcRef: srcRef, DstRef: dstRef, Platforms: platforms}}
+				if a.Chart && chartRepo {
+					up := ts[0]
+					t := chartUploadTask(src, dstHC, a)
+					up.Kind, up.SrcRef, up.DstRef, up.Platforms = journal.KindChartRepo, t.srcRef, t.dstRef, nil
+					ts = append(ts, up)
 				}
-				for _, r := range list {
-					// r.Name is "project/repo"
-					parts := strings.SplitN(r.Name, "/", 2)
-					repo := parts[len(parts)-1]
-
-					// apply include/exclude
-					if len(p.Includes) > 0 && !globAny(p.Includes, repo) {
-						continue
+
+				state := stateNew
+				if !syncForce {
+					if state, err = dstIdx.classify(a.DstProject, a.DstRepo, a.DstTag, a.Digest, platformChildren(a, platforms)); err != nil {
+						return fmt.Errorf("%s: %w", toReg, err)
 					}
-					if len(p.Excludes) > 0 && globAny(p.Excludes, repo) {
-						continue
+				}
+				counts[state]++
+				if state == stateUpToDate {
+					if verbose {
+						color.Cyan("up-to-date: %s (%s)", dstRef, a.Digest)
 					}
-
-					tgs := p.Tags
-					if len(tgs) == 0 {
-						tgs = []string{"*"}
+					for _, t := range ts {
+						rep.planned(t, report.UpToDate)
 					}
-					plan = append(plan, planItem{Repo: repo, Tags: tgs})
+					continue
 				}
-			}
-			if len(plan) == 0 {
-				color.Yellow("No repos matched by %s for project %q.", syncRulesPath, syncProject)
+				if _, ok := projectFrom[a.DstProject]; !ok {
+					projectFrom[a.DstProject] = projectOrigin{From: name, Project: a.Project}
+					projects = append(projects, a.DstProject)
+				}
+
 				if dryRun {
-					return nil
+					if a.Chart {
+						color.Yellow("[dry-run] (%s) skopeo copy %s -> %s (chart)", state, srcRef, dstRef)
+						if chartRepo {
+							color.Yellow("[dry-run] chartrepo upload %s-%s.tgz -> %s/%s", chartName(a.Repo), a.Tag, toReg, a.Project)
+						}
+					} else {
+						color.Yellow("[dry-run] (%s) skopeo copy %s -> %s%s", state, srcRef, dstRef, describePlatforms(a, platforms))
+					}
+					for _, t := range ts {
+						rep.planned(t, report.Planned)
+					}
+					continue
 				}
+				planned = append(planned, ts...)
 			}
-		} else {
-			// legacy --repo / --tags path
-			var repos []string
-			if syncRepo == "" {
-				list, err := srcHC.ListRepos(syncProject)
-				if err != nil {
-					return fmt.Errorf("list repos: %w", err)
-				}
-				for _, r := range list {
-					parts := strings.SplitN(r.Name, "/", 2)
-					repo := parts[len(parts)-1]
-					repos = append(repos, repo)
-				}
-			} else {
-				repos = []string{syncRepo}
+			color.Cyan("%s -> %s: %d new, %d changed, %d up-to-date",
+				name, toReg, counts[stateNew], counts[stateChanged], counts[stateUpToDate])
+		}
+
+		var creates []projectCreate
+		if syncCreateProjects {
+			if creates, err = planProjects(projects, projectFrom, sources, dstHC); err != nil {
+				return err
 			}
-			tgs := syncTags
-			if len(tgs) == 0 {
-				tgs = []string{"*"}
+		}
+
+		// Mirror: deletions are always previewed, and limited, before anything changes
+		var dels []mirrorDelete
+		dstHost := trimScheme(dstReg)
+		if syncMode == modeMirror {
+			if dels, err = planDeletions(order, sources, plans, rs, dstHC); err != nil {
+				return err
 			}
-			for _, r := range repos {
-				plan = append(plan, planItem{Repo: r, Tags: tgs})
+			previewDeletions(dels, dstHost, toReg)
+			if n := countTags(dels); syncMaxDeletes >= 0 && n > syncMaxDeletes {
+				msg := fmt.Sprintf("mirror: %d tag(s) to delete exceeds --max-deletes %d", n, syncMaxDeletes)
+				if !dryRun {
+					return fmt.Errorf("%s; review the deletions above and raise the limit to go ahead", msg)
+				}
+				color.Red("%s; the real run will refuse to start", msg)
 			}
 		}
 
-		// Resolve registry endpoints for copy
-		srcReg := fr.RegistryURL
-		if srcReg == "" {
-			srcReg = fr.URL // fallback for real Harbor where same host
+		if dryRun {
+			plannedProjects(creates, toReg, rep)
+			plannedDeletions(dels, dstHost, rep)
+			rep.write(report.Report{Command: "sync", From: fromReg, To: toReg, DryRun: true, Started: started})
+			return nil
 		}
-		dstReg := tr.RegistryURL
-		if dstReg == "" {
-			dstReg = tr.URL
+		if len(dels) > 0 {
+			if err := confirmDeletions(countTags(dels), toReg, syncYes); err != nil {
+				return err
+			}
+		}
+		if err := createProjects(dstHC, creates, toReg, rep); err != nil {
+			rep.write(report.Report{Command: "sync", From: fromReg, To: toReg, Started: started})
+			return err
 		}
 
-		// Build copy tasks
-		var tasks []copyTask
-
-		for _, item := range plan {
-			arts, err := srcHC.ListArtifacts(syncProject, item.Repo)
-			if err != nil {
-				color.Red("skip %s/%s: %v", syncProject, item.Repo, err)
-				continue
+		// a journal is only worth keeping when there is something to resume
+		if len(planned) == 0 {
+			color.Green("Nothing to copy.")
+		} else {
+			path := syncJournal
+			if path == "" {
+				if path, err = defaultJournalPath(); err != nil {
+					return err
+				}
 			}
+			var j *journal.Journal
+			if j, err = journal.Create(path, toReg); err != nil {
+				return fmt.Errorf("create journal: %w", err)
+			}
+			defer j.Close()
+			if _, err := j.Add(planned...); err != nil {
+				return fmt.Errorf("write journal: %w", err)
+			}
+			color.Cyan("Journal: %s", path)
 
-			for _, a := range arts {
-				for _, tg := range a.Tags {
-					tag := tg.Name
-					if !globAny(item.Tags, tag) {
-						continue
-					}
-
-					srcRef := fmt.Sprintf("docker://%s/%s/%s:%s",
-						trimScheme(srcReg), syncProject, item.Repo, tag)
-					dstRef := fmt.Sprintf("docker://%s/%s/%s:%s",
-						trimScheme(dstReg), syncProject, item.Repo, tag)
+			var exec executor.Executor
+			if exec, err = newExecutor(cfg, syncDockerNetwork, nil); err != nil {
+				return err
+			}
+			defer exec.Close()
 
-					if dryRun {
-						color.Yellow("[dry-run] skopeo copy %s -> %s", srcRef, dstRef)
-						continue
-					}
-					tasks = append(tasks, copyTask{srcRef: srcRef, dstRef: dstRef})
-				}
+			results := runJournal(j, cfg, exec, maxConcurrent, dstHC,
+				executor.Endpoint{User: tu, Pass: tp, Insecure: tr.Insecure}, rep)
+			err = summarizeJournal(j, results)
+			if verr := verifySync(j, cfg, rep); err == nil {
+				err = verr
 			}
 		}
-
-		// Execute tasks with worker pool
-		if !dryRun {
-			runCopies(tasks, cfg, syncDockerNetwork, fr.Insecure, tr.Insecure, fu, fp, tu, tp)
+		// deletions go last, once replacements are in place
+		if len(dels) > 0 {
+			failed := runDeletions(dstHC, dels, dstHost, rep)
+			n := countTags(dels)
+			fmt.Printf("Mirror: %s, %s\n", color.GreenString("%d tag(s) deleted", n-failed), color.RedString("%d failed", failed))
+			if failed > 0 && err == nil {
+				err = &exitCodeError{code: exitPartialFailure, err: fmt.Errorf("%d of %d tag deletion(s) failed", failed, n)}
+			}
+		}
+		rep.write(report.Report{Command: "sync", From: fromReg, To: toReg, Started: started})
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
 		}
-
 		return nil
 	},
 }
@@ -205,19 +402,38 @@ func init() {
 	syncCmd.Flags().BoolVar(&dryRun, "dry-run", true, "Print what would be copied, do not execute")
 	syncCmd.Flags().StringVar(&syncDockerNetwork, "docker-network", "", "Docker network for skopeo")
 	syncCmd.Flags().StringVar(&syncRulesPath, "rules", "", "Path to rules.yaml (overrides --repo/--tags)")
+	syncCmd.Flags().StringVar(&syncRuleSet, "rule-set", "", "Name of a rule_sets entry in the rules file (may span projects and source registries)")
+	syncCmd.Flags().StringSliceVar(&syncCharts, "charts", nil, "Helm chart name globs to sync from --project (images skip chart artifacts)")
+	syncCmd.Flags().StringSliceVar(&syncChartVersions, "chart-versions", nil, "Chart version globs (default: all)")
+	syncCmd.Flags().BoolVar(&syncChartRepo, "chartrepo", false, "Also upload charts as classic .tgz to the destination chart repository (Harbor before 2.8; skipped with a warning where the API is gone)")
+	syncCmd.Flags().BoolVar(&syncForce, "force", false, "Copy every matching tag, even when the destination already has the same digest")
+	syncCmd.Flags().StringVar(&syncJournal, "journal", "", "Where to write the task journal (default ~/.harair/journal/sync-<time>.jsonl)")
+	syncCmd.Flags().StringVar(&syncResume, "resume", "", "Continue the unfinished tasks of a journal instead of planning a new sync")
+	syncCmd.Flags().StringVar(&syncFailedFile, "failed-file", "", "Where to write failed tasks for `harair retry` (default: next to the journal)")
+	syncCmd.Flags().StringArrayVar(&syncReports, "report", nil, "Write a report as json=<path> or junit=<path> (repeatable)")
+	syncCmd.Flags().BoolVar(&syncVerify, "verify", false, "After copying, check that each copied tag's digest on the destination matches the source")
+	syncCmd.Flags().BoolVar(&syncVerifyBlobs, "verify-blobs", false, "With --verify, also check that every blob exists on the destination")
+	syncCmd.Flags().StringSliceVar(&syncPlatforms, "platforms", nil, "Platforms of multi-arch images to copy: all (default) or os/arch[/variant] list, e.g. linux/amd64,linux/arm64; rules may set their own. Subsets need skopeo_path: native")
+	syncCmd.Flags().StringVar(&syncMode, "mode", modeCopy, "copy: only add and update; mirror: also delete destination tags in scope that are gone from the source")
+	syncCmd.Flags().IntVar(&syncMaxDeletes, "max-deletes", 20, "With --mode mirror, refuse to run when more tags would be deleted (-1: no limit)")
+	syncCmd.Flags().BoolVar(&syncYes, "yes", false, "With --mode mirror, delete without asking (for non-interactive runs)")
+	syncCmd.Flags().BoolVar(&syncCreateProjects, "create-projects", false, "Create destination projects that do not exist yet, with the source project's visibility, quota, auto-scan and content-trust settings")
 	syncCmd.Flags().IntVar(&maxConcurrent, "concurrency", 2, "Number of parallel copy operations")
 }
 
 // ----- worker pool -----
-func runCopies(tasks []copyTask, cfg *config.Config, dockerNetwork string,
-	srcInsecure, dstInsecure bool, fu, fp, tu, tp string) {
-
+// runCopies runs tasks through exec on a pool of workers, retrying transient
+// failures per --retries. done, if set, is
+// called with each task's index and result as it finishes. The returned slice
+// holds one result per task, in task order.
+func runCopies(tasks []copyTask, exec executor.Executor, workers int, src, dst executor.Endpoint,
+	done func(i int, r copyResult)) []copyResult {
 	if len(tasks) == 0 {
 		color.Green("Nothing to copy.")
-		return
+		return nil
 	}
-	if maxConcurrent < 1 {
-		maxConcurrent = 1
+	if workers < 1 {
+		workers = 1
 	}
 
 	bar := progressbar.NewOptions(len(tasks),
@@ -229,28 +445,35 @@ func runCopies(tasks []copyTask, cfg *config.Config, dockerNetwork string,
 		progressbar.OptionClearOnFinish(),
 	)
 
-	taskCh := make(chan copyTask)
+	ctx := context.Background()
+	policy := retryPolicy()
+	taskCh := make(chan int)
 	doneCh := make(chan struct{})
+	results := make([]copyResult, len(tasks))
 
 	// spawn workers
-	for i := 0; i < maxConcurrent; i++ {
+	for i := 0; i < workers; i++ {
 		go func() {
-			for t := range taskCh {
-				args := buildSkopeoCopyArgs(
-					cfg.SkopeoPath,
-					dockerNetwork,
-					srcInsecure, dstInsecure,
-					fu, fp, tu, tp,
-					t.srcRef, t.dstRef,
-				)
-
-				out, err := shell.Run(cfg.SkopeoPath, args...)
-				if err != nil {
-					if strings.Contains(out+err.Error(), "manifest unknown") {
-						color.Yellow("skip (missing on source): %s", t.srcRef)
-					} else {
-						color.Red("copy failed: %v", err)
-					}
+			for i := range taskCh {
+				t := tasks[i]
+				req := executor.Request{SrcRef: t.srcRef, DstRef: t.dstRef, Src: src, Dst: dst, Platforms: t.platforms}
+				start := time.Now()
+				attempts, err := policy.Do(ctx, func() error { return exec.Copy(ctx, req) },
+					func(n int, err error, wait time.Duration) {
+						color.Yellow("retry %d/%d in %s (%s): %s", n, policy.Attempts-1, wait.Round(time.Millisecond), retry.Classify(err), t.srcRef)
+					})
+				results[i] = copyResult{task: t, err: err, attempts: attempts, class: retry.Classify(err), duration: time.Since(start)}
+				switch {
+				case err == nil:
+				case results[i].class == retry.NotFound:
+					results[i].outcome = copySkipped
+					color.Yellow("skip (missing on source): %s", t.srcRef)
+				default:
+					results[i].outcome = copyFailed
+					color.Red("copy failed (%s): %v", results[i].class, err)
+				}
+				if done != nil {
+					done(i, results[i])
 				}
 				bar.Add(1)
 			}
@@ -259,71 +482,30 @@ func runCopies(tasks []copyTask, cfg *config.Config, dockerNetwork string,
 	}
 
 	// feed tasks
-	for _, t := range tasks {
-		taskCh <- t
+	for i := range tasks {
+		taskCh <- i
 	}
 	close(taskCh)
 
 	// wait all
-	for i := 0; i < maxConcurrent; i++ {
+	for i := 0; i < workers; i++ {
 		<-doneCh
 	}
 
 	bar.Finish()
-	color.Green("Completed %d copy operation(s).", len(tasks))
 	time.Sleep(200 * time.Millisecond) // smooth finish
+	return results
 }
 
-// ----- helpers -----
-
-func buildSkopeoCopyArgs(
-	skopeoPath string,
-	dockerNetwork string,
-	srcInsecure, dstInsecure bool,
-	srcUser, srcPass, dstUser, dstPass string,
-	srcRef, dstRef string,
-) []string {
-	if strings.EqualFold(skopeoPath, "docker") {
-		args := []string{"run", "--rm"}
-		if dockerNetwork != "" {
-			args = append(args, "--network", dockerNetwork)
-		}
-		image := "quay.io/skopeo/stable"
-		args = append(args, image, "copy")
-		if srcInsecure {
-			args = append(args, "--src-tls-verify=false")
-		}
-		if dstInsecure {
-			args = append(args, "--dest-tls-verify=false")
-		}
-		if srcUser != "" || srcPass != "" {
-			args = append(args, "--src-creds", fmt.Sprintf("%s:%s", srcUser, srcPass))
-		}
-		if dstUser != "" || dstPass != "" {
-			args = append(args, "--dest-creds", fmt.Sprintf("%s:%s", dstUser, dstPass))
-		}
-		args = append(args, srcRef, dstRef)
-		return args
-	}
-
-	// Native skopeo on PATH
-	args := []string{"copy"}
-	if srcInsecure {
-		args = append(args, "--src-tls-verify=false")
-	}
-	if dstInsecure {
-		args = append(args, "--dest-tls-verify=false")
-	}
-	if srcUser != "" || srcPass != "" {
-		args = append(args, "--src-creds", fmt.Sprintf("%s:%s", srcUser, srcPass))
-	}
-	if dstUser != "" || dstPass != "" {
-		args = append(args, "--dest-creds", fmt.Sprintf("%s:%s", dstUser, dstPass))
-	}
-	args = append(args, srcRef, dstRef)
-	return args
+// newExecutor returns the copy backend selected by skopeo_path in config.yaml.
+// volumes are extra "host:container" bind mounts for container-wrapped skopeo
+// (used when one side of the copy is a local OCI layout).
+func newExecutor(cfg *config.Config, dockerNetwork string, volumes []string) (executor.Executor, error) {
+	return executor.New(cfg.SkopeoPath, executor.Options{Network: dockerNetwork, Volumes: volumes})
 }
 
+// ----- helpers -----
+
 func trimScheme(u string) string {
 	if strings.HasPrefix(u, "http://") {
 		return strings.TrimPrefix(u, "http://")
@@ -2,69 +2,109 @@ package cmd
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/hakantongur/harair/internal/config"
-	"github.com/hakantongur/harair/internal/shell"
+	"github.com/hakantongur/harair/internal/executor"
+	"github.com/hakantongur/harair/internal/journal"
+	"github.com/hakantongur/harair/internal/registry"
+	"github.com/hakantongur/harair/internal/report"
 	"github.com/spf13/cobra"
 )
 
 var (
-	fromRef       string
-	toRef         string
-	srcInsecure   bool
-	destInsecure  bool
-	reallyDoCopy  bool
-	dockerNetwork string
+	fromRef         string
+	toRef           string
+	srcInsecure     bool
+	destInsecure    bool
+	reallyDoCopy    bool
+	dockerNetwork   string
+	directReports   []string
+	directPlatforms []string
 )
 
 var syncDirectCmd = &cobra.Command{
 	Use:   "sync-direct",
 	Short: "Directly copy one image ref to another (no Harbor discovery)",
+	Long: `Directly copy one image ref to another (no Harbor discovery).
+
+A docker:// ref whose host is the registry_url (or url) of a registry in
+config.yaml uses that registry's credentials, resolved as in sync; refs to
+other hosts are copied without credentials.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if fromRef == "" || toRef == "" {
 			return fmt.Errorf("please pass --from and --to (e.g., docker://localhost:5001/demo/demo-repo:latest)")
 		}
+		started := time.Now().UTC()
+		rep, err := newTaskReport(directReports)
+		if err != nil {
+			return err
+		}
 		cfg, err := config.Load(cfgPath)
 		if err != nil {
 			return err
 		}
-		color.Cyan("Plan: skopeo copy %s -> %s", fromRef, toRef)
+		platforms, err := copyPlatforms(nil, directPlatforms)
+		if err != nil {
+			return err
+		}
+		if err := checkPlatformEngine(cfg, platforms); err != nil {
+			return err
+		}
+		task := journal.Task{Kind: journal.KindCopy, SrcRef: fromRef, DstRef: toRef, Platforms: platforms}
+		color.Cyan("Plan: skopeo copy %s -> %s%s", fromRef, toRef, platformNote(platforms))
 		if !reallyDoCopy {
 			color.Yellow("[dry-run] Not executing. Add --do to perform the copy.")
+			rep.planned(task, report.Planned)
+			rep.write(report.Report{Command: "sync-direct", DryRun: true, Started: started})
 			return nil
 		}
 
-		if cfg.SkopeoPath == "docker" || cfg.SkopeoPath == "Docker" {
-			args = append(args, "run", "--rm")
-			// 👇 add the network if requested
-			if dockerNetwork != "" {
-				args = append(args, "--network", dockerNetwork)
-			}
-			args = append(args, "quay.io/skopeo/stable", "copy")
-		} else {
-			args = append(args, "copy")
-		}
-		if srcInsecure {
-			args = append(args, "--src-tls-verify=false")
-		}
-		if destInsecure {
-			args = append(args, "--dest-tls-verify=false")
-		}
-		args = append(args, fromRef, toRef)
-
-		color.Green("Executing: %s %v", cfg.SkopeoPath, args)
-		out, err := shell.Run(cfg.SkopeoPath, args...)
+		exec, err := newExecutor(cfg, dockerNetwork, nil)
 		if err != nil {
-			return fmt.Errorf("copy failed: %w", err)
+			return err
 		}
-		if len(out) > 0 {
-			fmt.Println(out)
+		defer exec.Close()
+
+		color.Green("Executing: copy %s -> %s (%s)", fromRef, toRef, cfg.SkopeoPath)
+		res := runCopies([]copyTask{{srcRef: fromRef, dstRef: toRef, platforms: platforms}}, exec, 1,
+			directEndpoint(cfg, fromRef, srcInsecure), directEndpoint(cfg, toRef, destInsecure), nil)[0]
+		rep.executed(task, res)
+		rep.write(report.Report{Command: "sync-direct", Started: started})
+		if res.outcome != copySucceeded {
+			return fmt.Errorf("copy failed: %w", res.err)
 		}
+		color.Green("Copied %s -> %s", fromRef, toRef)
 		return nil
 	},
 }
 
+// directEndpoint returns the endpoint of a sync-direct ref, with the
+// credentials of the configured registry serving its host, if there is one.
+func directEndpoint(cfg *config.Config, ref string, insecure bool) executor.Endpoint {
+	ep := executor.Endpoint{Insecure: insecure}
+	r, err := registry.ParseRef(ref)
+	if err != nil || r.Transport != "docker" {
+		return ep
+	}
+	names := make([]string, 0, len(cfg.Registries))
+	for name := range cfg.Registries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		host := strings.TrimSuffix(trimScheme(registryURL(cfg.Registries[name])), "/")
+		if strings.EqualFold(host, r.Host) {
+			ep.User, ep.Pass, _ = getCreds(cfg, name)
+			break
+		}
+	}
+	return ep
+}
+
 func init() {
 	rootCmd.AddCommand(syncDirectCmd)
 	syncDirectCmd.Flags().StringVar(&fromRef, "from", "", "Source image ref (e.g., docker://localhost:5001/demo/repo:tag)")
@@ -72,5 +112,7 @@ func init() {
 	syncDirectCmd.Flags().BoolVar(&srcInsecure, "src-insecure", true, "Disable TLS verify for source")
 	syncDirectCmd.Flags().BoolVar(&destInsecure, "dst-insecure", true, "Disable TLS verify for destination")
 	syncDirectCmd.Flags().BoolVar(&reallyDoCopy, "do", false, "Actually perform the copy (otherwise dry-run)")
+	syncDirectCmd.Flags().StringArrayVar(&directReports, "report", nil, "Write a report as json=<path> or junit=<path> (repeatable)")
+	syncDirectCmd.Flags().StringSliceVar(&directPlatforms, "platforms", nil, "Platforms of a multi-arch image to copy: all (default) or os/arch[/variant] list. Subsets need skopeo_path: native")
 	syncDirectCmd.Flags().StringVar(&dockerNetwork, "docker-network", "", "Docker network to run skopeo on (container mode)")
 }
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hakantongur/harair/internal/executor"
+	"github.com/hakantongur/harair/internal/journal"
+	"github.com/hakantongur/harair/internal/retry"
+)
+
+// failing returns a Recorder Err that fails copies from refs containing a
+// key with its error; a "flaky" ref fails once with a network error.
+func failing(errs map[string]error) func(executor.Request) error {
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	return func(req executor.Request) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Contains(req.SrcRef, "flaky") && !seen[req.SrcRef] {
+			seen[req.SrcRef] = true
+			return errors.New("dial tcp: connection refused")
+		}
+		for k, err := range errs {
+			if strings.Contains(req.SrcRef, k) {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestRunCopies(t *testing.T) {
+	fastRetries(t, 2)
+	rec := &executor.Recorder{Err: failing(map[string]error{
+		"gone":   errors.New("reading manifest: manifest unknown"),
+		"denied": errors.New("writing manifest: unauthorized: authentication required"),
+	})}
+	tasks := []copyTask{
+		{srcRef: "docker://src/p/ok:v1", dstRef: "docker://dst/p/ok:v1", platforms: []string{"linux/amd64"}},
+		{srcRef: "docker://src/p/flaky:v1", dstRef: "docker://dst/p/flaky:v1"},
+		{srcRef: "docker://src/p/gone:v1", dstRef: "docker://dst/p/gone:v1"},
+		{srcRef: "docker://src/p/denied:v1", dstRef: "docker://dst/p/denied:v1"},
+	}
+	src, dst := executor.Endpoint{User: "u", Pass: "p"}, executor.Endpoint{Insecure: true}
+	var mu sync.Mutex
+	var done []int
+	results := runCopies(tasks, rec, 3, src, dst, func(i int, _ copyResult) {
+		mu.Lock()
+		done = append(done, i)
+		mu.Unlock()
+	})
+
+	want := []struct {
+		outcome  copyOutcome
+		class    retry.Class
+		attempts int
+	}{
+		{copySucceeded, retry.None, 1},
+		{copySucceeded, retry.None, 2},
+		{copySkipped, retry.NotFound, 1},
+		{copyFailed, retry.Auth, 1}, // not transient: no retry
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.task.srcRef != tasks[i].srcRef || r.outcome != w.outcome || r.class != w.class || r.attempts != w.attempts {
+			t.Errorf("%s: %s/%s after %d attempt(s), want %s/%s after %d",
+				tasks[i].srcRef, r.outcome, r.class, r.attempts, w.outcome, w.class, w.attempts)
+		}
+	}
+	slices.Sort(done)
+	if !slices.Equal(done, []int{0, 1, 2, 3}) {
+		t.Errorf("done called for %v", done)
+	}
+
+	reqs := rec.Requests()
+	if len(reqs) != 5 {
+		t.Fatalf("%d copies, want 5 (one retry)", len(reqs))
+	}
+	for _, req := range reqs {
+		if req.Src != src || req.Dst != dst {
+			t.Errorf("%s: endpoints %+v %+v", req.SrcRef, req.Src, req.Dst)
+		}
+		if strings.Contains(req.SrcRef, "/ok:") && !slices.Equal(req.Platforms, []string{"linux/amd64"}) {
+			t.Errorf("platforms %v not passed on", req.Platforms)
+		}
+	}
+
+	var ee *exitCodeError
+	if err := summarizeCopies(results); !errors.As(err, &ee) || ee.code != exitPartialFailure {
+		t.Errorf("summary: %v, want partial failure", err)
+	}
+}
+
+func TestJournalAndRetry(t *testing.T) {
+	fastRetries(t, 0)
+	src, dst := newFakeHarbor(t), newFakeHarbor(t)
+	cfg := testConfig(t, map[string]*fakeHarbor{"src": src, "dst": dst})
+	dir := t.TempDir()
+	old := syncFailedFile
+	syncFailedFile = filepath.Join(dir, "failed.json")
+	t.Cleanup(func() { syncFailedFile = old })
+
+	j, err := journal.Create(filepath.Join(dir, "sync.jsonl"), "dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	task := func(repo string) journal.Task {
+		return journal.Task{Kind: journal.KindCopy, From: "src", Project: "p", Repo: repo, Tag: "v1",
+			Digest: "sha256:" + repo, SrcRef: "docker://src/p/" + repo + ":v1", DstRef: "docker://dst/p/" + repo + ":v1"}
+	}
+	if _, err := j.Add(task("ok"), task("down"), task("gone")); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &executor.Recorder{Err: failing(map[string]error{
+		"down": errors.New("received unexpected HTTP status: 503 Service Unavailable"),
+		"gone": errors.New("manifest unknown"),
+	})}
+	results := runJournal(j, cfg, rec, 2, dst.client(), executor.Endpoint{User: "admin"}, nil)
+	for _, req := range rec.Requests() {
+		if !strings.Contains(req.SrcRef, "@sha256:") || req.Src.User != "admin" {
+			t.Errorf("%s: want the planned digest and config.yaml credentials, got user %q", req.SrcRef, req.Src.User)
+		}
+	}
+	for id, want := range map[int]journal.State{1: journal.Done, 2: journal.Failed, 3: journal.Skipped} {
+		if state, _, _ := j.State(id); state != want {
+			t.Errorf("task %d: %s, want %s", id, state, want)
+		}
+	}
+
+	// the failed task is written out for `harair retry` ...
+	if err := summarizeJournal(j, results); err == nil {
+		t.Error("summary: no error for a failed task")
+	}
+	f, err := journal.ReadFailed(syncFailedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Tasks) != 1 || f.Tasks[0].Repo != "down" || f.Tasks[0].Class != string(retry.Server) ||
+		f.Tasks[0].To != "dst" || f.Tasks[0].SrcRef != "docker://src/p/down@sha256:down" {
+		t.Fatalf("failed tasks %+v", f.Tasks)
+	}
+
+	// ... and stays unfinished in the journal, for --resume
+	if todo := j.Unfinished(); len(todo) != 1 || todo[0].Repo != "down" {
+		t.Errorf("unfinished %+v", todo)
+	}
+
+	// retry runs exactly that task
+	rec = &executor.Recorder{}
+	results = runTasks([]journal.Task{f.Tasks[0].Task}, cfg, rec, 1, dst.client(), executor.Endpoint{}, func(journal.Task, copyResult) {})
+	if err := summarizeCopies(results); err != nil {
+		t.Errorf("retry: %v", err)
+	}
+	if reqs := rec.Requests(); len(reqs) != 1 || reqs[0].SrcRef != "docker://src/p/down@sha256:down" {
+		t.Errorf("retried %+v", reqs)
+	}
+}
+
+func TestSyncJournalOnlyWithCopies(t *testing.T) {
+	fastRetries(t, 0)
+	src, dst := newFakeHarbor(t), newFakeHarbor(t)
+	src.add("p", "app", imageArt("sha256:a", "v1"))
+	dst.add("p", "app", imageArt("sha256:a", "v1"))
+	cfg := testConfig(t, map[string]*fakeHarbor{"src": src, "dst": dst})
+	cfg.SkopeoPath = "native"
+	journals := filepath.Join(os.Getenv("HOME"), ".harair", "journal")
+	args := []string{"sync", "src", "dst", "--project", "p", "--dry-run=false"}
+
+	if code, out := runCmd(t, cfg, args...); code != exitOK || !strings.Contains(out, "Nothing to copy") {
+		t.Errorf("up to date: exit %d\n%s", code, out)
+	}
+	if _, err := os.Stat(journals); !os.IsNotExist(err) {
+		t.Errorf("up to date: journal written (%v)", err)
+	}
+
+	// a copy to make: it fails, and the journal keeps it
+	src.add("p", "app", imageArt("sha256:b", "v2"))
+	src.Fail["v2"] = http.StatusForbidden // the registry API, not Harbor's
+	code, _ := runCmd(t, cfg, args...)
+	files, _ := os.ReadDir(journals)
+	if code != exitTotalFailure || len(files) != 2 { // the journal and its failed tasks
+		t.Fatalf("one copy: exit %d, files %v", code, files)
+	}
+	j, err := journal.Open(filepath.Join(journals, files[1].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if todo := j.Unfinished(); len(todo) != 1 || todo[0].Tag != "v2" {
+		t.Errorf("journal %+v", j.Tasks)
+	}
+}
+
+func TestRetryDefaultsToDryRun(t *testing.T) {
+	fastRetries(t, 0)
+	src, dst := newFakeHarbor(t), newFakeHarbor(t)
+	src.Fail["v2"] = http.StatusForbidden
+	cfg := testConfig(t, map[string]*fakeHarbor{"src": src, "dst": dst})
+	cfg.SkopeoPath = "native"
+	path := filepath.Join(t.TempDir(), "failed.json")
+	task := journal.FailedTask{To: "dst", Class: "server", Task: journal.Task{ID: 1, Kind: journal.KindCopy, From: "src",
+		SrcRef: "docker://" + src.Listener.Addr().String() + "/p/app@sha256:a", DstRef: "docker://" + dst.Listener.Addr().String() + "/p/app:v1"}}
+	if err := journal.WriteFailed(path, []journal.FailedTask{task}); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, out := runCmd(t, cfg, "retry", path); code != exitOK || !strings.Contains(out, "[dry-run] (server) copy") {
+		t.Errorf("retry: exit %d\n%s", code, out)
+	}
+	if code, _ := runCmd(t, cfg, "retry", path, "--dry-run=false"); code != exitTotalFailure {
+		t.Errorf("retry --dry-run=false: exit %d, want %d", code, exitTotalFailure)
+	}
+	if f, err := journal.ReadFailed(path); err != nil || len(f.Tasks) != 1 || f.Tasks[0].Class != "auth" {
+		t.Errorf("failed file after retry: %+v %v", f, err)
+	}
+}
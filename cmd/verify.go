@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/hakantongur/harair/internal/config"
+	"github.com/hakantongur/harair/internal/journal"
+	"github.com/hakantongur/harair/internal/registry"
+	"github.com/hakantongur/harair/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyJournal     string
+	verifyProject     string
+	verifyRepo        string
+	verifyTags        []string
+	verifyRulesPath   string
+	verifyBlobs       bool
+	verifyReports     []string
+	verifyConcurrency int
+	verifyPlatforms   []string
+)
+
+// Verification states, as printed and written to reports.
+const (
+	verifyOK            = report.Verified
+	verifyMismatch      = "mismatch"       // digests differ
+	verifyMissing       = "missing"        // tag not on the destination
+	verifyMissingBlobs  = "missing-blobs"  // manifest there, blobs or child manifests not
+	verifySourceMissing = "source-missing" // nothing to compare against
+	verifyError         = "error"
+)
+
+// verifyResult is the verification of one copied tag.
+type verifyResult struct {
+	task   journal.Task
+	status string
+	v      *registry.Verification
+	err    error
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [from-registry] [to-registry]",
+	Short: "Check that copied tags on the destination match the source",
+	Long: `Check that copied tags on the destination match the source.
+
+For every tag, the manifest is fetched from both registries and the digests
+of the bytes are compared. --blobs also walks the destination manifest and
+HEADs every config and layer blob (and child manifest of an index).
+
+Verify the tags of a project, like sync would copy them:
+  harair verify harbor1 harbor2 --project demo [--repo web] [--tags 'v1*']
+or the tags a sync copied, from its journal:
+  harair verify --journal ~/.harair/journal/sync-<time>.jsonl
+
+Exit codes: 0 when everything matches, 5 on a mismatch or missing content,
+1 on any other error.`,
+	Args: cobra.RangeArgs(0, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		started := time.Now().UTC()
+		rep, err := newTaskReport(verifyReports)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+
+		var fromReg, toReg string
+		var tasks []journal.Task
+		if verifyJournal != "" {
+			if len(args) > 0 {
+				return fmt.Errorf("--journal takes no registry arguments (they are in the journal)")
+			}
+			j, err := journal.Open(verifyJournal)
+			if err != nil {
+				return fmt.Errorf("open journal: %w", err)
+			}
+			toReg = j.Header.To
+			tasks = copiedTasks(j)
+			j.Close()
+		} else {
+			if len(args) != 2 {
+				return fmt.Errorf("accepts 2 arg(s) [from-registry] [to-registry] (or --journal <path>), received %d", len(args))
+			}
+			if verifyProject == "" {
+				return fmt.Errorf("please provide --project or --journal")
+			}
+			fromReg, toReg = args[0], args[1]
+			if tasks, err = verifyPlan(cfg, fromReg, toReg); err != nil {
+				return err
+			}
+		}
+
+		results := verifyTasks(cfg, tasks, toReg, verifyBlobs, verifyConcurrency)
+		for _, r := range results {
+			rep.planned(r.task, r.status)
+		}
+		rep.verified(results)
+		err = summarizeVerify(results)
+		rep.write(report.Report{Command: "verify", From: fromReg, To: toReg, Started: started})
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().StringVar(&verifyJournal, "journal", "", "Verify the tags copied by the sync that wrote this journal")
+	verifyCmd.Flags().StringVar(&verifyProject, "project", "", "Project to verify")
+	verifyCmd.Flags().StringVar(&verifyRepo, "repo", "", "Specific repo to verify (optional)")
+	verifyCmd.Flags().StringSliceVar(&verifyTags, "tags", nil, "Tag globs to verify (default: all)")
+	verifyCmd.Flags().StringVar(&verifyRulesPath, "rules", "", "Path to rules.yaml (overrides --repo/--tags)")
+	verifyCmd.Flags().BoolVar(&verifyBlobs, "blobs", false, "Also check that every blob exists on the destination (HEAD per layer)")
+	verifyCmd.Flags().StringArrayVar(&verifyReports, "report", nil, "Write a report as json=<path> or junit=<path> (repeatable)")
+	verifyCmd.Flags().StringSliceVar(&verifyPlatforms, "platforms", nil, "Platforms the tags were copied with (as sync --platforms), so filtered indexes compare equal")
+	verifyCmd.Flags().IntVar(&verifyConcurrency, "concurrency", 4, "Number of parallel verifications")
+}
+
+// verifyPlan lists the image tags of --project on fromReg as copy tasks to toReg.
+func verifyPlan(cfg *config.Config, fromReg, toReg string) ([]journal.Task, error) {
+	src, err := newRegistrySource(cfg, fromReg)
+	if err != nil {
+		return nil, err
+	}
+	tr, ok := cfg.Registries[toReg]
+	if !ok {
+		return nil, fmt.Errorf("registry %q not in %s", toReg, cfgPath)
+	}
+	plan, err := buildPlan(src.HC, verifyProject, verifyRepo, verifyTags, verifyRulesPath)
+	if err != nil {
+		return nil, err
+	}
+	srcHost, dstHost := trimScheme(registryURL(src.Reg)), trimScheme(registryURL(tr))
+	var tasks []journal.Task
+	for _, a := range resolvePlan(src.HC, plan) {
+		platforms, err := copyPlatforms(a.Platforms, verifyPlatforms)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, journal.Task{Kind: journal.KindCopy, From: fromReg, Platforms: platforms,
+			Project: a.Project, Repo: a.Repo, Tag: a.Tag, Digest: a.Digest, Size: a.Size,
+			SrcRef: fmt.Sprintf("docker://%s/%s/%s:%s", srcHost, a.Project, a.Repo, a.Tag),
+			DstRef: fmt.Sprintf("docker://%s/%s/%s:%s", dstHost, a.DstProject, a.DstRepo, a.DstTag)})
+	}
+	return tasks, nil
+}
+
+// copiedTasks returns the copy tasks of j that finished successfully.
+func copiedTasks(j *journal.Journal) []journal.Task {
+	var out []journal.Task
+	for _, t := range j.Tasks {
+		if state, _, _ := j.State(t.ID); state == journal.Done && t.Kind == journal.KindCopy {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// verifySync verifies the copies recorded as done in j, for sync --verify.
+func verifySync(j *journal.Journal, cfg *config.Config, rep *taskReport) error {
+	if !syncVerify {
+		return nil
+	}
+	results := verifyTasks(cfg, copiedTasks(j), j.Header.To, syncVerifyBlobs, maxConcurrent)
+	rep.verified(results)
+	return summarizeVerify(results)
+}
+
+// verifyTasks checks every task's destination against its source on a pool
+// of workers, retrying transient errors per --retries. Results are in task order.
+func verifyTasks(cfg *config.Config, tasks []journal.Task, to string, blobs bool, workers int) []verifyResult {
+	results := make([]verifyResult, len(tasks))
+	if len(tasks) == 0 {
+		color.Green("Nothing to verify.")
+		return results
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	color.Cyan("Verifying %d tag(s) on %s...", len(tasks), to)
+
+	// one client per registry, so tokens are shared between workers
+	var mu sync.Mutex
+	clients := map[string]*registry.Client{}
+	client := func(name string) (*registry.Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if c, ok := clients[name]; ok {
+			return c, nil
+		}
+		r, ok := cfg.Registries[name]
+		if !ok {
+			return nil, fmt.Errorf("registry %q not in %s", name, cfgPath)
+		}
+		user, pass, _ := getCreds(cfg, name)
+		c := registry.NewClient(trimScheme(registryURL(r)), user, pass, r.Insecure)
+		clients[name] = c
+		return c, nil
+	}
+
+	ctx := context.Background()
+	policy := retryPolicy()
+	taskCh := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range taskCh {
+				t := tasks[i]
+				var v *registry.Verification
+				_, err := policy.Do(ctx, func() (err error) {
+					v, err = verifyTask(ctx, client, t, to, blobs)
+					return err
+				}, nil)
+				results[i] = verifyResult{task: t, v: v, err: err, status: verifyStatus(v, err)}
+				if verbose && results[i].status == verifyOK {
+					color.Green("verified: %s (%s)", t.DstRef, v.Dest.Digest)
+				}
+			}
+		}()
+	}
+	for i := range tasks {
+		taskCh <- i
+	}
+	close(taskCh)
+	wg.Wait()
+	return results
+}
+
+// verifyTask compares one task's destination with its source.
+func verifyTask(ctx context.Context, client func(name string) (*registry.Client, error),
+	t journal.Task, to string, blobs bool) (*registry.Verification, error) {
+
+	sc, err := client(t.From)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := client(to)
+	if err != nil {
+		return nil, err
+	}
+	sr, err := registry.ParseRef(t.SrcRef)
+	if err != nil {
+		return nil, err
+	}
+	dr, err := registry.ParseRef(t.DstRef)
+	if err != nil {
+		return nil, err
+	}
+	src, srcRef := registry.Open(sr, func(string) *registry.Client { return sc })
+	dst, dstRef := registry.Open(dr, func(string) *registry.Client { return dc })
+	platforms, err := registry.ParsePlatforms(t.Platforms)
+	if err != nil {
+		return nil, err
+	}
+	return registry.Verify(ctx, src, dst, srcRef, dstRef, registry.VerifyOptions{Blobs: blobs, Platforms: platforms})
+}
+
+func verifyStatus(v *registry.Verification, err error) string {
+	switch {
+	case err != nil && registry.IsNotFound(err):
+		return verifySourceMissing
+	case err != nil:
+		return verifyError
+	case v.Dest.Digest == "":
+		return verifyMissing
+	case v.Source.Digest != v.Dest.Digest:
+		return verifyMismatch
+	case len(v.Missing) > 0:
+		return verifyMissingBlobs
+	}
+	return verifyOK
+}
+
+// summarizeVerify prints every problem and the tally, and returns an error
+// carrying exitVerifyFailed when anything did not verify.
+func summarizeVerify(results []verifyResult) error {
+	counts := map[string]int{}
+	blobs := 0
+	for _, r := range results {
+		counts[r.status]++
+		if r.v != nil {
+			blobs += r.v.Blobs
+		}
+		switch r.status {
+		case verifyOK:
+		case verifyMismatch:
+			color.Red("  MISMATCH %s: source %s, destination %s", r.task.DstRef, r.v.Source.Digest, r.v.Dest.Digest)
+		case verifyMissingBlobs:
+			color.Red("  MISSING BLOBS %s: %d of %d", r.task.DstRef, len(r.v.Missing), r.v.Blobs)
+			for _, d := range r.v.Missing {
+				color.Red("    %s (%s)", d.Digest, humanSize(d.Size))
+			}
+		case verifyMissing:
+			color.Red("  MISSING %s (source %s)", r.task.DstRef, r.v.Source.Digest)
+		case verifySourceMissing:
+			color.Red("  SOURCE MISSING %s: %v", r.task.SrcRef, r.err)
+		default:
+			color.Red("  ERROR %s: %v", r.task.DstRef, r.err)
+		}
+	}
+
+	line := fmt.Sprintf("Verify: %s, %s, %s, %s, %s",
+		color.GreenString("%d verified", counts[verifyOK]),
+		color.RedString("%d mismatched", counts[verifyMismatch]),
+		color.RedString("%d missing", counts[verifyMissing]),
+		color.RedString("%d with missing blobs", counts[verifyMissingBlobs]),
+		color.RedString("%d errors", counts[verifyError]+counts[verifySourceMissing]))
+	if blobs > 0 {
+		line += fmt.Sprintf(" (%d blobs checked)", blobs)
+	}
+	fmt.Println(line)
+
+	if bad := len(results) - counts[verifyOK]; bad > 0 {
+		return &exitCodeError{code: exitVerifyFailed, err: fmt.Errorf("%d of %d tag(s) failed verification", bad, len(results))}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package authstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Entry is one registry's stored credentials.
+type Entry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Store maps registry names (as in config.yaml) to credentials.
+type Store map[string]Entry
+
+// SecretFunc returns the passphrase or key-file contents the store key is
+// derived from. It is only called when a secret is actually needed.
+type SecretFunc func() ([]byte, error)
+
+const (
+	formatVersion = 1
+	kdfPBKDF2     = "pbkdf2-sha256"
+	iterations    = 600_000
+)
+
+// envelope is the on-disk form of an encrypted store.
+type envelope struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// ErrNoSecret is returned by a SecretFunc when no passphrase or key file is available.
+var ErrNoSecret = errors.New("auth store is encrypted: set HARAIR_PASSPHRASE, auth_key_file or HARAIR_KEY_FILE")
+
+// Load reads the store at path. A missing or empty file is an empty store.
+// Plaintext stores (written before encryption existed) load without a
+// secret; encrypted reports which kind was found.
+func Load(path string, secret SecretFunc) (s Store, encrypted bool, err error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(b) == 0) {
+		return Store{}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var env envelope
+	if json.Unmarshal(b, &env) == nil && env.Ciphertext != nil {
+		sec, err := secret()
+		if err != nil {
+			return nil, true, err
+		}
+		plain, err := env.open(sec)
+		if err != nil {
+			return nil, true, err
+		}
+		b = plain
+		encrypted = true
+	}
+
+	s = Store{}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, encrypted, fmt.Errorf("parse auth store: %w", err)
+	}
+	return s, encrypted, nil
+}
+
+// Save encrypts s with a key derived from secret and writes it to path.
+func Save(path string, s Store, secret []byte) error {
+	if len(secret) == 0 {
+		return ErrNoSecret
+	}
+	plain, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	env, err := seal(plain, secret)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// write-then-rename so an interrupted save never leaves a truncated store
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func seal(plain, secret []byte) (*envelope, error) {
+	env := &envelope{
+		Version:    formatVersion,
+		KDF:        kdfPBKDF2,
+		Iterations: iterations,
+		Salt:       make([]byte, 16),
+	}
+	if _, err := rand.Read(env.Salt); err != nil {
+		return nil, err
+	}
+	gcm, err := env.aead(secret)
+	if err != nil {
+		return nil, err
+	}
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return nil, err
+	}
+	env.Ciphertext = gcm.Seal(nil, env.Nonce, plain, nil)
+	return env, nil
+}
+
+func (env *envelope) open(secret []byte) ([]byte, error) {
+	if env.Version != formatVersion || env.KDF != kdfPBKDF2 {
+		return nil, fmt.Errorf("unsupported auth store format (version %d, kdf %q)", env.Version, env.KDF)
+	}
+	gcm, err := env.aead(secret)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, env.Nonce, env.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("cannot decrypt auth store: wrong passphrase or key file")
+	}
+	return plain, nil
+}
+
+func (env *envelope) aead(secret []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(secret, env.Salt, env.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keys caches derived keys for the life of the process: PBKDF2 is slow on
+// purpose, and a run loads the store once per registry. Entries are keyed by
+// a hash of the inputs, so the secret itself is not kept.
+var (
+	keysMu sync.Mutex
+	keys   = map[[sha256.Size]byte][]byte{}
+)
+
+func deriveKey(secret, salt []byte, iter int) ([]byte, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d:%d:", iter, len(salt))
+	h.Write(salt)
+	h.Write(secret)
+	var id [sha256.Size]byte
+	h.Sum(id[:0])
+
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if key, ok := keys[id]; ok {
+		return key, nil
+	}
+	key, err := pbkdf2.Key(sha256.New, string(secret), salt, iter, 32)
+	if err != nil {
+		return nil, err
+	}
+	keys[id] = key
+	return key, nil
+}
@@ -0,0 +1,107 @@
+package authstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func secretOf(s string) SecretFunc {
+	return func() ([]byte, error) { return []byte(s), nil }
+}
+
+// noSecret fails the test if the store asks for a secret.
+func noSecret(t *testing.T) SecretFunc {
+	return func() ([]byte, error) {
+		t.Error("secret requested")
+		return nil, ErrNoSecret
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	want := Store{"harbor1": {Username: "robot$ci", Password: "s3cret"}}
+	if err := Save(path, want, []byte("correct horse")); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(path)
+	if strings.Contains(string(b), "s3cret") || strings.Contains(string(b), "robot$ci") {
+		t.Fatalf("store not encrypted: %s", b)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode %v, want 0600", fi.Mode().Perm())
+	}
+
+	got, encrypted, err := Load(path, secretOf("correct horse"))
+	if err != nil || !encrypted || got["harbor1"] != want["harbor1"] {
+		t.Errorf("Load = %v %v %v", got, encrypted, err)
+	}
+	if err := Save(path, want, nil); !errors.Is(err, ErrNoSecret) {
+		t.Errorf("Save without a secret: %v", err)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	if err := Save(path, Store{"h": {Username: "u", Password: "p"}}, []byte("right")); err != nil {
+		t.Fatal(err)
+	}
+	_, encrypted, err := Load(path, secretOf("wrong"))
+	if err == nil || !encrypted || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Load = %v %v, want wrong passphrase", encrypted, err)
+	}
+	if _, _, err := Load(path, func() ([]byte, error) { return nil, ErrNoSecret }); !errors.Is(err, ErrNoSecret) {
+		t.Errorf("no secret: err = %v", err)
+	}
+}
+
+func TestPlaintextMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	if err := os.WriteFile(path, []byte(`{"harbor1":{"username":"admin","password":"old"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, encrypted, err := Load(path, noSecret(t))
+	if err != nil || encrypted || s["harbor1"].Password != "old" {
+		t.Fatalf("plaintext Load = %v %v %v", s, encrypted, err)
+	}
+	// what `auth encrypt` does
+	if err := Save(path, s, []byte("new passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	s, encrypted, err = Load(path, secretOf("new passphrase"))
+	if err != nil || !encrypted || s["harbor1"].Password != "old" {
+		t.Errorf("after migration: %v %v %v", s, encrypted, err)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	for _, content := range []*string{nil, new(string)} {
+		path := filepath.Join(t.TempDir(), "auth.json")
+		if content != nil {
+			os.WriteFile(path, []byte(*content), 0o600)
+		}
+		if s, encrypted, err := Load(path, noSecret(t)); err != nil || encrypted || len(s) != 0 {
+			t.Errorf("Load = %v %v %v, want an empty store", s, encrypted, err)
+		}
+	}
+}
+
+func TestDeriveKeyCached(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	k1, err := deriveKey([]byte("pass"), salt, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(keys)
+	k2, _ := deriveKey([]byte("pass"), salt, 1000)
+	if string(k1) != string(k2) || len(keys) != n {
+		t.Error("second derivation was not served from the cache")
+	}
+	k3, _ := deriveKey([]byte("pass"), salt, 1001)
+	k4, _ := deriveKey([]byte("other"), salt, 1000)
+	if string(k3) == string(k1) || string(k4) == string(k1) {
+		t.Error("cache mixed up keys of different inputs")
+	}
+}
@@ -0,0 +1,75 @@
+package bundle
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPackUnpack(t *testing.T) {
+	for _, name := range []string{"bundle.tar", "bundle.tar.gz"} {
+		t.Run(name, func(t *testing.T) {
+			src := t.TempDir()
+			manifest, _, _ := writeBundle(t, src)
+			archive := filepath.Join(t.TempDir(), name)
+			if err := Pack(src, archive); err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			if err := Unpack(archive, dir); err != nil {
+				t.Fatal(err)
+			}
+			m, err := ReadManifest(dir)
+			if err != nil || m.Source != "harbor1" || len(m.Artifacts) != 1 || m.Artifacts[0].Digest != manifest.Digest {
+				t.Fatalf("manifest %+v %v", m, err)
+			}
+			layout := filepath.Join(dir, LayoutDir)
+			idx, err := ReadIndex(layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Verify(layout, idx[m.Artifacts[0].Ref]); err != nil {
+				t.Errorf("unpacked bundle: %v", err)
+			}
+		})
+	}
+}
+
+func TestUnpackUnsafe(t *testing.T) {
+	for _, name := range []string{"../evil", "oci/../../evil", "/tmp/evil", ".."} {
+		t.Run(name, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), "bad.tar")
+			f, err := os.Create(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tw := tar.NewWriter(f)
+			tw.WriteHeader(&tar.Header{Name: "bundle.json", Mode: 0o644, Size: 2, Typeflag: tar.TypeReg})
+			tw.Write([]byte("{}"))
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 4, Typeflag: tar.TypeReg})
+			tw.Write([]byte("evil"))
+			tw.Close()
+			f.Close()
+
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "bundle")
+			if err := Unpack(archive, dir); err == nil || !strings.Contains(err.Error(), "unsafe path") {
+				t.Errorf("err %v, want unsafe path", err)
+			}
+			if _, err := os.Stat(filepath.Join(parent, "evil")); !os.IsNotExist(err) {
+				t.Error("entry written outside the bundle")
+			}
+		})
+	}
+}
+
+func TestUnpackNotGzip(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "bundle.tgz")
+	os.WriteFile(archive, []byte("plain text"), 0o644)
+	if err := Unpack(archive, t.TempDir()); err == nil {
+		t.Error("no error for a .tgz that isn't gzip")
+	}
+}
@@ -0,0 +1,103 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// ManifestFile is the bundle manifest at the bundle root.
+	ManifestFile = "bundle.json"
+	// LayoutDir is the OCI image-layout directory inside the bundle.
+	LayoutDir = "oci"
+
+	// refNameAnnotation names an image inside an OCI layout's index.json.
+	refNameAnnotation = "org.opencontainers.image.ref.name"
+)
+
+// Manifest describes what a bundle carries and where it came from.
+type Manifest struct {
+	Version   int        `json:"version"`
+	Created   time.Time  `json:"created"`
+	Source    string     `json:"source"` // registry name in config.yaml
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// Artifact is one tagged image stored in the bundle's OCI layout.
+type Artifact struct {
+	SourceRef    string `json:"source_ref"` // docker://host/project/repo:tag
+	Project      string `json:"project"`
+	Repo         string `json:"repo"`
+	Tag          string `json:"tag"`
+	Ref          string `json:"ref"`           // ref.name inside the OCI layout
+	SourceDigest string `json:"source_digest"` // digest reported by the source Harbor
+	Digest       string `json:"digest"`        // manifest digest inside the layout
+	MediaType    string `json:"media_type"`
+	Size         int64  `json:"size"` // manifest + all referenced blobs
+	// Platforms kept from a multi-arch index; empty when all were exported.
+	Platforms []string `json:"platforms,omitempty"`
+}
+
+// Descriptor is the subset of an OCI content descriptor we need.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// LayoutRef is the ref.name used for an artifact inside the OCI layout.
+func LayoutRef(project, repo, tag string) string {
+	return fmt.Sprintf("%s/%s:%s", project, repo, tag)
+}
+
+func WriteManifest(dir string, m *Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFile), b, 0o644)
+}
+
+func ReadManifest(dir string) (*Manifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", ManifestFile, err)
+	}
+	return &m, nil
+}
+
+// ReadIndex returns the layout's index.json entries keyed by ref.name.
+func ReadIndex(layout string) (map[string]Descriptor, error) {
+	b, err := os.ReadFile(filepath.Join(layout, "index.json"))
+	if err != nil {
+		return nil, err
+	}
+	var idx struct {
+		Manifests []Descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil, fmt.Errorf("parse index.json: %w", err)
+	}
+	out := make(map[string]Descriptor, len(idx.Manifests))
+	for _, d := range idx.Manifests {
+		if name := d.Annotations[refNameAnnotation]; name != "" {
+			out[name] = d
+		}
+	}
+	return out, nil
+}
+
+// IsArchive reports whether path names a bundle tarball rather than a directory.
+func IsArchive(path string) bool {
+	p := strings.ToLower(path)
+	return strings.HasSuffix(p, ".tar") || strings.HasSuffix(p, ".tar.gz") || strings.HasSuffix(p, ".tgz")
+}
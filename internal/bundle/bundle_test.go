@@ -0,0 +1,83 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeBlob stores b in the layout and returns its descriptor.
+func writeBlob(t *testing.T, layout, mediaType string, b []byte) Descriptor {
+	t.Helper()
+	sum := sha256.Sum256(b)
+	d := Descriptor{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(b))}
+	if err := os.MkdirAll(filepath.Join(layout, "blobs", "sha256"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blobPath(layout, d.Digest), b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// writeBundle writes a bundle directory with one image, p/app:v1, and
+// returns the descriptors of its manifest, config and layer.
+func writeBundle(t *testing.T, dir string) (manifest, config, layer Descriptor) {
+	t.Helper()
+	layout := filepath.Join(dir, LayoutDir)
+	config = writeBlob(t, layout, "application/vnd.oci.image.config.v1+json", []byte(`{"architecture":"amd64","os":"linux"}`))
+	layer = writeBlob(t, layout, "application/vnd.oci.image.layer.v1.tar+gzip", []byte("layer content"))
+	mb, _ := json.Marshal(map[string]any{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config": config, "layers": []Descriptor{layer}})
+	manifest = writeBlob(t, layout, "application/vnd.oci.image.manifest.v1+json", mb)
+
+	ref := manifest
+	ref.Annotations = map[string]string{refNameAnnotation: LayoutRef("p", "app", "v1")}
+	untagged := manifest // entries without a ref.name are not listed
+	idx, _ := json.Marshal(map[string]any{"schemaVersion": 2, "manifests": []Descriptor{ref, untagged}})
+	if err := os.WriteFile(filepath.Join(layout, "index.json"), idx, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(layout, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := &Manifest{Version: 1, Created: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), Source: "harbor1",
+		Artifacts: []Artifact{{Project: "p", Repo: "app", Tag: "v1", Ref: LayoutRef("p", "app", "v1"), Digest: manifest.Digest}}}
+	if err := WriteManifest(dir, m); err != nil {
+		t.Fatal(err)
+	}
+	return manifest, config, layer
+}
+
+func TestReadIndex(t *testing.T) {
+	dir := t.TempDir()
+	manifest, _, _ := writeBundle(t, dir)
+	idx, err := ReadIndex(filepath.Join(dir, LayoutDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx) != 1 || idx["p/app:v1"].Digest != manifest.Digest {
+		t.Errorf("index %+v", idx)
+	}
+
+	if _, err := ReadIndex(t.TempDir()); !os.IsNotExist(err) {
+		t.Errorf("no index.json: err %v, want not exist", err)
+	}
+	bad := t.TempDir()
+	os.WriteFile(filepath.Join(bad, "index.json"), []byte("{"), 0o644)
+	if _, err := ReadIndex(bad); err == nil {
+		t.Error("broken index.json: no error")
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	for path, want := range map[string]bool{"b.tar": true, "b.tar.gz": true, "B.TGZ": true, "bundle": false, "b.gz": false} {
+		if IsArchive(path) != want {
+			t.Errorf("IsArchive(%q) = %v", path, !want)
+		}
+	}
+}
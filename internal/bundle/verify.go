@@ -0,0 +1,104 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Verify walks the content reachable from d (index → manifests → config and
+// layers), checks every blob's size and sha256 against its descriptor and
+// returns the total number of bytes referenced.
+func Verify(layout string, d Descriptor) (int64, error) {
+	seen := map[string]bool{}
+	return verify(layout, d, seen)
+}
+
+func verify(layout string, d Descriptor, seen map[string]bool) (int64, error) {
+	if seen[d.Digest] {
+		return 0, nil
+	}
+	seen[d.Digest] = true
+
+	if err := checkBlob(layout, d); err != nil {
+		return 0, err
+	}
+	total := d.Size
+	if !isManifest(d.MediaType) {
+		return total, nil
+	}
+
+	b, err := os.ReadFile(blobPath(layout, d.Digest))
+	if err != nil {
+		return 0, err
+	}
+	var m struct {
+		Config    *Descriptor  `json:"config"`
+		Layers    []Descriptor `json:"layers"`
+		Manifests []Descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return 0, fmt.Errorf("parse manifest %s: %w", d.Digest, err)
+	}
+
+	var children []Descriptor
+	if m.Config != nil {
+		children = append(children, *m.Config)
+	}
+	children = append(children, m.Layers...)
+	children = append(children, m.Manifests...)
+	for _, c := range children {
+		n, err := verify(layout, c, seen)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func checkBlob(layout string, d Descriptor) error {
+	algo, hexsum, ok := strings.Cut(d.Digest, ":")
+	if !ok || algo != "sha256" {
+		return fmt.Errorf("unsupported digest %q", d.Digest)
+	}
+	f, err := os.Open(blobPath(layout, d.Digest))
+	if err != nil {
+		return fmt.Errorf("blob %s: %w", d.Digest, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return fmt.Errorf("blob %s: %w", d.Digest, err)
+	}
+	if n != d.Size {
+		return fmt.Errorf("blob %s: size %d, expected %d", d.Digest, n, d.Size)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != hexsum {
+		return fmt.Errorf("blob %s: digest mismatch (got sha256:%s)", d.Digest, got)
+	}
+	return nil
+}
+
+func blobPath(layout, digest string) string {
+	algo, hexsum, _ := strings.Cut(digest, ":")
+	return filepath.Join(layout, "blobs", algo, hexsum)
+}
+
+func isManifest(mediaType string) bool {
+	switch mediaType {
+	case "application/vnd.oci.image.manifest.v1+json",
+		"application/vnd.oci.image.index.v1+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+		"application/vnd.docker.distribution.manifest.list.v2+json":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	manifest, config, layer := writeBundle(t, dir)
+	layout := filepath.Join(dir, LayoutDir)
+	n, err := Verify(layout, manifest)
+	if err != nil || n != manifest.Size+config.Size+layer.Size {
+		t.Errorf("Verify = %d %v, want %d", n, err, manifest.Size+config.Size+layer.Size)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		damage func(path string) error
+		want   string
+	}{
+		{"corrupted", func(p string) error { return os.WriteFile(p, []byte("LAYER CONTENT"), 0o644) }, "digest mismatch"},
+		{"truncated", func(p string) error { return os.Truncate(p, 3) }, "size 3"},
+		{"missing", os.Remove, "no such file"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			manifest, _, layer := writeBundle(t, dir)
+			layout := filepath.Join(dir, LayoutDir)
+			if err := tc.damage(blobPath(layout, layer.Digest)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Verify(layout, manifest); err == nil || !strings.Contains(err.Error(), layer.Digest) || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err %v, want %q for %s", err, tc.want, layer.Digest)
+			}
+		})
+	}
+
+	if _, err := Verify(layout, Descriptor{Digest: "md5:abc"}); err == nil {
+		t.Error("unsupported digest: no error")
+	}
+}
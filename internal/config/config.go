@@ -16,9 +16,11 @@ type Registry struct {
 }
 
 type Config struct {
-	SkopeoPath string              `yaml:"skopeo_path"` // "docker" or "skopeo"
-	Registries map[string]Registry `yaml:"registries"`
-	AuthStore  string              `yaml:"auth_store,omitempty"` // optional: where `login` persists creds
+	SkopeoPath  string              `yaml:"skopeo_path"` // "docker" or "podman" (containerized skopeo), "skopeo" (or a path to it), or "native"
+	HelmPath    string              `yaml:"helm_path"`   // helm binary, used to pull classic chart .tgz
+	Registries  map[string]Registry `yaml:"registries"`
+	AuthStore   string              `yaml:"auth_store,omitempty"`    // optional: where `login` persists creds
+	AuthKeyFile string              `yaml:"auth_key_file,omitempty"` // optional: key file for the auth store (else passphrase)
 }
 
 func Load(path string) (*Config, error) {
@@ -0,0 +1,105 @@
+package dockercfg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hakantongur/harair/internal/shell"
+)
+
+// file is the subset of docker's config.json we read.
+type file struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// Path returns $DOCKER_CONFIG/config.json, or ~/.docker/config.json.
+func Path() string {
+	if d := os.Getenv("DOCKER_CONFIG"); d != "" {
+		return filepath.Join(d, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// Lookup returns credentials for host from docker's config.json, asking the
+// host's credential helper (credHelpers, then credsStore) before falling back
+// to inline "auths" entries. found is false when nothing matches.
+func Lookup(host string) (user, pass string, found bool, err error) {
+	p := Path()
+	if p == "" {
+		return "", "", false, nil
+	}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return "", "", false, fmt.Errorf("parse %s: %w", p, err)
+	}
+
+	if helper := f.CredHelpers[host]; helper != "" {
+		return fromHelper(helper, host)
+	}
+
+	for _, key := range []string{host, "https://" + host, "http://" + host} {
+		e, ok := f.Auths[key]
+		if !ok {
+			continue
+		}
+		if e.Auth != "" {
+			raw, err := base64.StdEncoding.DecodeString(e.Auth)
+			if err != nil {
+				return "", "", false, fmt.Errorf("%s: bad auth for %s: %w", p, key, err)
+			}
+			u, pw, _ := strings.Cut(string(raw), ":")
+			return u, pw, true, nil
+		}
+		if e.Username != "" || e.Password != "" {
+			return e.Username, e.Password, true, nil
+		}
+		// empty entry: credentials live in the global store
+		break
+	}
+
+	if f.CredsStore != "" {
+		return fromHelper(f.CredsStore, host)
+	}
+	return "", "", false, nil
+}
+
+// fromHelper runs docker-credential-<helper> get for host.
+func fromHelper(helper, host string) (string, string, bool, error) {
+	out, err := shell.RunInput(host, "docker-credential-"+helper, "get")
+	if err != nil {
+		if strings.Contains(err.Error(), "credentials not found") {
+			return "", "", false, nil
+		}
+		return "", "", false, err
+	}
+	var resp struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		return "", "", false, fmt.Errorf("docker-credential-%s: %w", helper, err)
+	}
+	return resp.Username, resp.Secret, true, nil
+}
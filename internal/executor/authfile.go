@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// AuthMount is where the auth file directory is mounted inside the skopeo container.
+const AuthMount = "/harair-auth"
+
+// AuthFiles is a private temp directory of containers-auth.json files, used
+// to hand credentials to skopeo and helm without putting them on argv.
+type AuthFiles struct {
+	Dir string
+
+	mu    sync.Mutex
+	files map[string]string // host+creds -> path
+}
+
+func NewAuthFiles() (*AuthFiles, error) {
+	dir, err := os.MkdirTemp("", "harair-auth-")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &AuthFiles{Dir: dir, files: map[string]string{}}, nil
+}
+
+// Write stores user/pass for host in an auth file and returns its path on
+// the host, or "" when there are no credentials to store. Files are reused
+// for the same host and credentials.
+func (a *AuthFiles) Write(host, user, pass string) (string, error) {
+	if user == "" && pass == "" {
+		return "", nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := host + "\x00" + user + "\x00" + pass
+	if p, ok := a.files[key]; ok {
+		return p, nil
+	}
+
+	type authEntry struct {
+		Auth string `json:"auth"`
+	}
+	doc := map[string]map[string]authEntry{
+		"auths": {host: {Auth: base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))}},
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	p := filepath.Join(a.Dir, fmt.Sprintf("auth-%d.json", len(a.files)))
+	if err := os.WriteFile(p, b, 0o600); err != nil {
+		return "", err
+	}
+	a.files[key] = p
+	return p, nil
+}
+
+// InContainer maps a path written by Write to its location under AuthMount.
+func (a *AuthFiles) InContainer(p string) string {
+	if p == "" {
+		return ""
+	}
+	return AuthMount + "/" + filepath.Base(p)
+}
+
+func (a *AuthFiles) Close() error {
+	return os.RemoveAll(a.Dir)
+}
+
+// refHost returns the registry host of a "docker://host/repo:tag" ref, or ""
+// for other transports.
+func refHost(ref string) string {
+	rest, ok := strings.CutPrefix(ref, "docker://")
+	if !ok {
+		return ""
+	}
+	host, _, _ := strings.Cut(rest, "/")
+	return host
+}
@@ -0,0 +1,92 @@
+// Package executor runs single image copies. The backend (skopeo in a
+// container, skopeo on PATH, or the built-in registry client) is chosen by
+// skopeo_path in config.yaml; callers only deal with Request and Executor.
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hakantongur/harair/internal/registry"
+)
+
+// SkopeoImage is the image run by the container executors.
+const SkopeoImage = "quay.io/skopeo/stable"
+
+// Endpoint holds credentials and TLS settings for one side of a copy.
+type Endpoint struct {
+	User     string
+	Pass     string
+	Insecure bool // skip TLS verification (and allow plain http)
+}
+
+// Request is one copy: SrcRef to DstRef, each a docker:// or oci: reference.
+type Request struct {
+	SrcRef string
+	DstRef string
+	Src    Endpoint
+	Dst    Endpoint
+	// Platforms limits a multi-platform image to these os/arch[/variant]
+	// entries; empty copies the whole index with every platform. Only the
+	// native engine supports it (see ErrPlatforms).
+	Platforms []string
+}
+
+// Executor copies an image from one reference to another. Copy must be safe
+// for concurrent use. Errors for images missing on the source satisfy
+// registry.IsNotFound.
+type Executor interface {
+	Copy(ctx context.Context, req Request) error
+	// Close releases resources such as temporary auth files.
+	Close() error
+}
+
+// ErrPlatforms is returned by the skopeo executors for requests limited to
+// some platforms: skopeo copies one platform or all of them, never a subset.
+var ErrPlatforms = errors.New("platform subsets need skopeo_path: native")
+
+// Options configure the container executors.
+type Options struct {
+	Network string   // container network (--network)
+	Volumes []string // extra "host:container" bind mounts
+}
+
+// New returns the executor selected by skopeo_path: "docker" or "podman"
+// run skopeo in a container, "native" uses the built-in registry client,
+// anything else is the path of a skopeo binary.
+func New(skopeoPath string, opts Options) (Executor, error) {
+	switch {
+	case IsNative(skopeoPath):
+		return NewNative(), nil
+	case IsContainer(skopeoPath):
+		return NewContainer(strings.ToLower(skopeoPath), opts)
+	case skopeoPath == "":
+		return NewSkopeo("skopeo")
+	default:
+		return NewSkopeo(skopeoPath)
+	}
+}
+
+// IsNative reports whether skopeo_path selects the built-in copy engine.
+func IsNative(skopeoPath string) bool {
+	return strings.EqualFold(skopeoPath, "native")
+}
+
+// IsContainer reports whether skopeo_path runs skopeo in a container, so
+// local paths must be bind-mounted (see Options.Volumes).
+func IsContainer(skopeoPath string) bool {
+	return strings.EqualFold(skopeoPath, "docker") || strings.EqualFold(skopeoPath, "podman")
+}
+
+// notFound marks err as a missing-source error when skopeo's output says so.
+func notFound(out string, err error) error {
+	if err == nil || registry.IsNotFound(err) {
+		return err
+	}
+	if strings.Contains(out+err.Error(), "manifest unknown") {
+		return fmt.Errorf("%w (%w)", err, registry.ErrNotFound)
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/hakantongur/harair/internal/registry"
+	"github.com/hakantongur/harair/internal/shell"
+)
+
+// Native copies with the built-in registry client. Clients are shared
+// across concurrent copies so bearer tokens are fetched once per scope.
+type Native struct {
+	mu      sync.Mutex
+	clients map[string]*registry.Client
+}
+
+func NewNative() *Native {
+	return &Native{clients: map[string]*registry.Client{}}
+}
+
+func (n *Native) client(host string, e Endpoint) *registry.Client {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	key := fmt.Sprintf("%s|%s|%s|%t", host, e.User, e.Pass, e.Insecure)
+	if c, ok := n.clients[key]; ok {
+		return c
+	}
+	c := registry.NewClient(host, e.User, e.Pass, e.Insecure)
+	n.clients[key] = c
+	return c
+}
+
+func (n *Native) Copy(ctx context.Context, req Request) error {
+	sr, err := registry.ParseRef(req.SrcRef)
+	if err != nil {
+		return err
+	}
+	dr, err := registry.ParseRef(req.DstRef)
+	if err != nil {
+		return err
+	}
+	src, srcRef := registry.Open(sr, func(host string) *registry.Client { return n.client(host, req.Src) })
+	dst, dstRef := registry.Open(dr, func(host string) *registry.Client { return n.client(host, req.Dst) })
+
+	platforms, err := registry.ParsePlatforms(req.Platforms)
+	if err != nil {
+		return err
+	}
+	opts := registry.CopyOptions{Platforms: platforms}
+	if sr.Transport == "docker" && dr.Transport == "docker" && sr.Host == dr.Host {
+		opts.MountFrom = sr.Repo
+	}
+	if shell.Verbose {
+		opts.OnBlob = func(d registry.Descriptor, action registry.BlobAction) {
+			fmt.Fprintf(os.Stderr, "[native] %s: %s %s (%d bytes)\n", req.DstRef, action, d.Digest, d.Size)
+		}
+	}
+	_, err = registry.Copy(ctx, src, dst, srcRef, dstRef, opts)
+	return err
+}
+
+func (n *Native) Close() error { return nil }
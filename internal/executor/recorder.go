@@ -0,0 +1,35 @@
+package executor
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder is a fake Executor that records requests instead of copying,
+// for exercising planning logic without a container runtime or registry.
+type Recorder struct {
+	// Err, if set, decides the result of each copy.
+	Err func(Request) error
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+func (r *Recorder) Copy(ctx context.Context, req Request) error {
+	r.mu.Lock()
+	r.requests = append(r.requests, req)
+	r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err(req)
+	}
+	return nil
+}
+
+// Requests returns the recorded requests in the order they were made.
+func (r *Recorder) Requests() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Request(nil), r.requests...)
+}
+
+func (r *Recorder) Close() error { return nil }
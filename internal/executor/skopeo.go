@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hakantongur/harair/internal/shell"
+)
+
+// Skopeo runs a skopeo binary on the host.
+//
+// skopeo copies either one platform of an index or all of them (--all), so
+// requests for a list of platforms fail with ErrPlatforms.
+type Skopeo struct {
+	Path string
+	auth *AuthFiles
+}
+
+func NewSkopeo(path string) (*Skopeo, error) {
+	auth, err := NewAuthFiles()
+	if err != nil {
+		return nil, fmt.Errorf("auth file: %w", err)
+	}
+	return &Skopeo{Path: path, auth: auth}, nil
+}
+
+func (s *Skopeo) Copy(ctx context.Context, req Request) error {
+	if len(req.Platforms) > 0 {
+		return ErrPlatforms
+	}
+	srcAuth, dstAuth, err := writeAuth(s.auth, req)
+	if err != nil {
+		return err
+	}
+	out, err := shell.RunContext(ctx, s.Path, SkopeoCopyArgs(req, srcAuth, dstAuth)...)
+	return notFound(out, err)
+}
+
+func (s *Skopeo) Close() error { return s.auth.Close() }
+
+// Container runs skopeo in a throwaway container (docker or podman). Auth
+// files and Options.Volumes are bind-mounted into it. Like Skopeo, it can't
+// copy a list of platforms.
+type Container struct {
+	Runtime string // "docker" or "podman"
+	Image   string
+	Options
+	auth *AuthFiles
+}
+
+func NewContainer(runtime string, opts Options) (*Container, error) {
+	auth, err := NewAuthFiles()
+	if err != nil {
+		return nil, fmt.Errorf("auth file: %w", err)
+	}
+	return &Container{Runtime: runtime, Image: SkopeoImage, Options: opts, auth: auth}, nil
+}
+
+func (c *Container) Copy(ctx context.Context, req Request) error {
+	if len(req.Platforms) > 0 {
+		return ErrPlatforms
+	}
+	srcAuth, dstAuth, err := writeAuth(c.auth, req)
+	if err != nil {
+		return err
+	}
+	out, err := shell.RunContext(ctx, c.Runtime, c.Args(req, c.auth.InContainer(srcAuth), c.auth.InContainer(dstAuth))...)
+	return notFound(out, err)
+}
+
+// Args returns the container runtime argv for req, given in-container auth
+// file paths.
+func (c *Container) Args(req Request, srcAuth, dstAuth string) []string {
+	args := []string{"run", "--rm"}
+	if c.Network != "" {
+		args = append(args, "--network", c.Network)
+	}
+	for _, v := range c.Volumes {
+		args = append(args, "-v", v)
+	}
+	if srcAuth != "" || dstAuth != "" {
+		args = append(args, "-v", c.auth.Dir+":"+AuthMount+":ro")
+	}
+	args = append(args, c.Image)
+	return append(args, SkopeoCopyArgs(req, srcAuth, dstAuth)...)
+}
+
+func (c *Container) Close() error { return c.auth.Close() }
+
+// SkopeoCopyArgs returns "copy [flags] src dst" for skopeo.
+func SkopeoCopyArgs(req Request, srcAuthFile, dstAuthFile string) []string {
+	args := []string{"copy"}
+	if len(req.Platforms) == 0 {
+		args = append(args, "--all") // every platform of an index, not just the host's
+	}
+	if req.Src.Insecure {
+		args = append(args, "--src-tls-verify=false")
+	}
+	if req.Dst.Insecure {
+		args = append(args, "--dest-tls-verify=false")
+	}
+	if srcAuthFile != "" {
+		args = append(args, "--src-authfile", srcAuthFile)
+	}
+	if dstAuthFile != "" {
+		args = append(args, "--dest-authfile", dstAuthFile)
+	}
+	return append(args, req.SrcRef, req.DstRef)
+}
+
+// writeAuth writes the source and destination auth files for req.
+// Credentials go to skopeo through these files, never through argv.
+func writeAuth(auth *AuthFiles, req Request) (src, dst string, err error) {
+	if src, err = auth.Write(refHost(req.SrcRef), req.Src.User, req.Src.Pass); err != nil {
+		return "", "", fmt.Errorf("auth file: %w", err)
+	}
+	if dst, err = auth.Write(refHost(req.DstRef), req.Dst.User, req.Dst.Pass); err != nil {
+		return "", "", fmt.Errorf("auth file: %w", err)
+	}
+	return src, dst, nil
+}
@@ -0,0 +1,123 @@
+package executor
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hakantongur/harair/internal/shell"
+)
+
+// fakeSkopeo writes a skopeo stand-in that prints the auth files it is given
+// to stderr, logs their paths and exits with code.
+func fakeSkopeo(t *testing.T, code string) (path, log string) {
+	t.Helper()
+	dir := t.TempDir()
+	path, log = filepath.Join(dir, "skopeo"), filepath.Join(dir, "authfiles")
+	script := `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--src-authfile|--dest-authfile) cat "$2" >&2; echo "$2" >>` + log + `; shift ;;
+	esac
+	shift
+done
+exit ` + code + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path, log
+}
+
+func TestSkopeoAuthFiles(t *testing.T) {
+	req := Request{SrcRef: "docker://src.local/p/app:v1", DstRef: "docker://dst.local/p/app:v1",
+		Src: Endpoint{User: "puller", Pass: "src-Secret1"}, Dst: Endpoint{User: "pusher", Pass: "dst-Secret2"}}
+	shell.RegisterCredentials(req.Src.User, req.Src.Pass)
+	shell.RegisterCredentials(req.Dst.User, req.Dst.Pass)
+
+	for _, tc := range []struct {
+		name string
+		code string
+	}{
+		{"success", "0"},
+		{"error", "1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bin, log := fakeSkopeo(t, tc.code)
+			s, err := NewSkopeo(bin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.Copy(context.Background(), req)
+			if (err != nil) != (tc.code != "0") {
+				t.Fatalf("err = %v", err)
+			}
+			if err != nil {
+				for _, leak := range []string{"src-Secret1", "dst-Secret2",
+					base64.StdEncoding.EncodeToString([]byte("puller:src-Secret1"))} {
+					if strings.Contains(err.Error(), leak) {
+						t.Errorf("error leaks %q: %v", leak, err)
+					}
+				}
+			}
+
+			b, _ := os.ReadFile(log)
+			files := strings.Fields(string(b))
+			if len(files) != 2 {
+				t.Fatalf("auth files %v, want source and destination", files)
+			}
+			for _, f := range files {
+				if fi, err := os.Stat(f); err != nil || fi.Mode().Perm() != 0o600 {
+					t.Errorf("%s before Close: %v %v", f, fi, err)
+				}
+			}
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range append(files, s.auth.Dir) {
+				if _, err := os.Stat(f); !os.IsNotExist(err) {
+					t.Errorf("%s left behind after Close", f)
+				}
+			}
+		})
+	}
+}
+
+func TestSkopeoPlatforms(t *testing.T) {
+	s, err := NewSkopeo("/nonexistent/skopeo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	req := Request{SrcRef: "docker://a/p/x:v1", DstRef: "docker://b/p/x:v1", Platforms: []string{"linux/amd64"}}
+	if err := s.Copy(context.Background(), req); !errors.Is(err, ErrPlatforms) {
+		t.Errorf("err = %v, want ErrPlatforms", err)
+	}
+}
+
+func TestAuthFilesWrite(t *testing.T) {
+	a, err := NewAuthFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if p, err := a.Write("h", "", ""); p != "" || err != nil {
+		t.Errorf("no credentials: %q %v, want no file", p, err)
+	}
+	p1, _ := a.Write("h", "u", "p")
+	p2, _ := a.Write("h", "u", "p")
+	p3, _ := a.Write("h", "u", "other")
+	if p1 == "" || p1 != p2 || p1 == p3 {
+		t.Errorf("paths %q %q %q; want reuse for the same credentials only", p1, p2, p3)
+	}
+	b, _ := os.ReadFile(p1)
+	if want := `{"auths":{"h":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("u:p")) + `"}}}`; string(b) != want {
+		t.Errorf("auth file %s, want %s", b, want)
+	}
+	if got := a.InContainer(p1); got != AuthMount+"/"+filepath.Base(p1) {
+		t.Errorf("InContainer = %q", got)
+	}
+}
@@ -1,11 +1,17 @@
 package harbor
 
 import (
+	"bytes"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -63,51 +69,353 @@ func (c *Client) getJSON(u string, out any) error {
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode/100 != 2 {
-		return fmt.Errorf("harbor GET %s: status %s", u, resp.Status)
+		return &StatusError{Method: http.MethodGet, URL: u, Status: resp.Status, Code: resp.StatusCode}
 	}
 	return json.NewDecoder(resp.Body).Decode(out)
 }
 
+func (c *Client) delete(u string) error {
+	return c.send(http.MethodDelete, u, nil, nil)
+}
+
+// send makes an API call with in (if not nil) as the JSON body and decodes
+// the answer into out (if not nil). Project references in the path are
+// names, never ids.
+func (c *Client) send(method, u string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, _ := http.NewRequest(method, u, body)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Is-Resource-Name", "true")
+	if c.User != "" || c.Pass != "" {
+		req.SetBasicAuth(c.User, c.Pass)
+	}
+	resp, err := c.httpc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{Method: method, URL: u, Status: resp.Status, Code: resp.StatusCode,
+			Body: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// StatusError is a non-2xx answer from the Harbor API.
+type StatusError struct {
+	Method string
+	URL    string
+	Status string // e.g. "404 Not Found"
+	Code   int
+	Body   string // start of the response body, if read
+}
+
+func (e *StatusError) Error() string {
+	s := fmt.Sprintf("harbor %s %s: status %s", e.Method, e.URL, e.Status)
+	if e.Body != "" {
+		s += " " + e.Body
+	}
+	return s
+}
+
+// IsNotFound reports whether err is a 404 from the Harbor API.
+func IsNotFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == http.StatusNotFound
+}
+
 // --- API shapes we use ---
 
 type Repository struct {
-	Name string `json:"name"` // "project/repo" or just "repo"
+	Name          string    `json:"name" yaml:"name"` // "project/repo" or just "repo"
+	ArtifactCount int       `json:"artifact_count" yaml:"artifact_count"`
+	PullCount     int       `json:"pull_count" yaml:"pull_count"`
+	CreationTime  time.Time `json:"creation_time" yaml:"creation_time"`
+	UpdateTime    time.Time `json:"update_time" yaml:"update_time"`
 }
 
 type Tag struct {
-	Name string `json:"name"`
+	Name      string    `json:"name" yaml:"name"`
+	PushTime  time.Time `json:"push_time" yaml:"push_time"`
+	PullTime  time.Time `json:"pull_time" yaml:"pull_time"`
+	Immutable bool      `json:"immutable" yaml:"immutable"`
+}
+
+type Label struct {
+	ID          int    `json:"id" yaml:"id"`
+	Name        string `json:"name" yaml:"name"`
+	Color       string `json:"color,omitempty" yaml:"color,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Scope       string `json:"scope,omitempty" yaml:"scope,omitempty"` // "g" global, "p" project
+}
+
+// Reference is a child manifest of a multi-platform artifact.
+type Reference struct {
+	ChildDigest string    `json:"child_digest" yaml:"child_digest"`
+	Platform    *Platform `json:"platform,omitempty" yaml:"platform,omitempty"`
+}
+
+type Platform struct {
+	OS           string `json:"os" yaml:"os"`
+	Architecture string `json:"architecture" yaml:"architecture"`
+	Variant      string `json:"variant,omitempty" yaml:"variant,omitempty"`
 }
 
 type Artifact struct {
-	Digest string `json:"digest"`
-	Tags   []Tag  `json:"tags"`
+	ID                int         `json:"id" yaml:"id"`
+	Digest            string      `json:"digest" yaml:"digest"`
+	Size              int64       `json:"size" yaml:"size"`
+	Type              string      `json:"type" yaml:"type"` // "IMAGE", "CHART", ...
+	MediaType         string      `json:"media_type" yaml:"media_type"`
+	ManifestMediaType string      `json:"manifest_media_type" yaml:"manifest_media_type"`
+	PushTime          time.Time   `json:"push_time" yaml:"push_time"`
+	PullTime          time.Time   `json:"pull_time" yaml:"pull_time"`
+	Tags              []Tag       `json:"tags" yaml:"tags"`
+	Labels            []Label     `json:"labels" yaml:"labels"`
+	References        []Reference `json:"references,omitempty" yaml:"references,omitempty"` // child manifests of an index
+}
+
+// Platforms returns os/arch[/variant] of each child of an index artifact;
+// it is empty for single-platform images.
+func (a Artifact) Platforms() []string {
+	var out []string
+	for _, r := range a.References {
+		if p := r.Platform; p != nil && p.OS != "" && p.OS != "unknown" {
+			s := p.OS + "/" + p.Architecture
+			if p.Variant != "" {
+				s += "/" + p.Variant
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// TagNames returns the names of the artifact's tags.
+func (a Artifact) TagNames() []string {
+	names := make([]string, 0, len(a.Tags))
+	for _, t := range a.Tags {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// IsChart reports whether the artifact is a Helm chart stored as OCI.
+func (a Artifact) IsChart() bool {
+	return strings.EqualFold(a.Type, "CHART")
+}
+
+type Project struct {
+	ProjectID    int               `json:"project_id" yaml:"project_id"`
+	Name         string            `json:"name" yaml:"name"`
+	OwnerName    string            `json:"owner_name,omitempty" yaml:"owner_name,omitempty"`
+	RepoCount    int               `json:"repo_count" yaml:"repo_count"`
+	ChartCount   int               `json:"chart_count" yaml:"chart_count"`
+	Metadata     map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"` // "public": "true", ...
+	CreationTime time.Time         `json:"creation_time" yaml:"creation_time"`
+	UpdateTime   time.Time         `json:"update_time" yaml:"update_time"`
+}
+
+// Public reports whether the project is public.
+func (p Project) Public() bool {
+	return p.Metadata["public"] == "true"
+}
+
+// ProjectReq is the body of project create and update calls. Metadata
+// values are strings, as Harbor returns them ("public": "true", ...).
+type ProjectReq struct {
+	Name         string            `json:"project_name,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	StorageLimit *int64            `json:"storage_limit,omitempty"` // bytes, -1 unlimited; create only
+}
+
+// Quota is a project's storage quota; -1 in Hard means unlimited.
+type Quota struct {
+	ID  int `json:"id"`
+	Ref struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"ref"`
+	Hard map[string]int64 `json:"hard"` // "storage": bytes
+	Used map[string]int64 `json:"used"`
+}
+
+// SearchResult is what Harbor's global search returns.
+type SearchResult struct {
+	Projects     []Project          `json:"project" yaml:"projects"`
+	Repositories []SearchRepository `json:"repository" yaml:"repositories"`
+}
+
+type SearchRepository struct {
+	ProjectName    string `json:"project_name" yaml:"project_name"`
+	ProjectPublic  bool   `json:"project_public" yaml:"project_public"`
+	RepositoryName string `json:"repository_name" yaml:"repository_name"` // "project/repo"
+	PullCount      int    `json:"pull_count" yaml:"pull_count"`
+	ArtifactCount  int    `json:"artifact_count" yaml:"artifact_count"`
+}
+
+type User struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Admin    bool   `json:"sysadmin_flag"`
 }
 
 // --- API methods ---
 
+// CurrentUser returns the user the client authenticates as. Harbor answers
+// 401 for bad credentials, which makes this a cheap login check.
+func (c *Client) CurrentUser() (*User, error) {
+	var u User
+	if err := c.getJSON(c.Base+"/api/v2.0/users/current", &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// ListProjects returns the projects visible to the client. q is an optional
+// Harbor query filter (e.g. "name=~web"); see the Harbor API docs for q.
+func (c *Client) ListProjects(q string) ([]Project, error) {
+	var all []Project
+	err := paginate(func(page, pageSize int) (int, error) {
+		var chunk []Project
+		u := fmt.Sprintf("%s/api/v2.0/projects?page=%d&page_size=%d%s", c.Base, page, pageSize, queryParam(q))
+		if err := c.getJSON(u, &chunk); err != nil {
+			return 0, err
+		}
+		all = append(all, chunk...)
+		return len(chunk), nil
+	})
+	return all, err
+}
+
+// projectURL is the API URL of a project, by name.
+func (c *Client) projectURL(name string) string {
+	return c.Base + "/api/v2.0/projects/" + url.PathEscape(name)
+}
+
+// GetProject returns the project called name; a missing project is a 404
+// (see IsNotFound).
+func (c *Client) GetProject(name string) (*Project, error) {
+	var p Project
+	if err := c.send(http.MethodGet, c.projectURL(name), nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// CreateProject creates the project req.Name with its metadata and quota.
+func (c *Client) CreateProject(req ProjectReq) error {
+	return c.send(http.MethodPost, c.Base+"/api/v2.0/projects", req, nil)
+}
+
+// UpdateProject sets the metadata of project name; keys not in req keep
+// their values. The quota is not changed.
+func (c *Client) UpdateProject(name string, req ProjectReq) error {
+	req.Name, req.StorageLimit = "", nil
+	return c.send(http.MethodPut, c.projectURL(name), req, nil)
+}
+
+// ProjectQuota returns the storage quota of the project with id projectID.
+func (c *Client) ProjectQuota(projectID int) (*Quota, error) {
+	var qs []Quota
+	u := fmt.Sprintf("%s/api/v2.0/quotas?reference=project&reference_id=%d", c.Base, projectID)
+	if err := c.getJSON(u, &qs); err != nil {
+		return nil, err
+	}
+	if len(qs) == 0 {
+		return nil, fmt.Errorf("no quota for project id %d", projectID)
+	}
+	return &qs[0], nil
+}
+
+// ListProjectQuotas returns project storage quotas keyed by project name.
+func (c *Client) ListProjectQuotas() (map[string]Quota, error) {
+	out := map[string]Quota{}
+	err := paginate(func(page, pageSize int) (int, error) {
+		var chunk []Quota
+		u := fmt.Sprintf("%s/api/v2.0/quotas?reference=project&page=%d&page_size=%d", c.Base, page, pageSize)
+		if err := c.getJSON(u, &chunk); err != nil {
+			return 0, err
+		}
+		for _, q := range chunk {
+			out[q.Ref.Name] = q
+		}
+		return len(chunk), nil
+	})
+	return out, err
+}
+
+// Search runs Harbor's global search for projects and repositories.
+func (c *Client) Search(term string) (*SearchResult, error) {
+	var r SearchResult
+	if err := c.getJSON(c.Base+"/api/v2.0/search?q="+url.QueryEscape(term), &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
 func (c *Client) ListRepos(project string) ([]Repository, error) {
-	const pageSize = 100
-	page := 1
-	var all []Repository
+	return c.ListReposQuery(project, "")
+}
 
-	for {
+// ListReposQuery is ListRepos with a Harbor q filter.
+func (c *Client) ListReposQuery(project, q string) ([]Repository, error) {
+	var all []Repository
+	err := paginate(func(page, pageSize int) (int, error) {
 		var chunk []Repository
-		u := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories?page=%d&page_size=%d",
-			c.Base, url.PathEscape(project), page, pageSize)
-
+		u := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories?page=%d&page_size=%d%s",
+			c.Base, url.PathEscape(project), page, pageSize, queryParam(q))
 		if err := c.getJSON(u, &chunk); err != nil {
-			return nil, err
+			return 0, err
 		}
 		all = append(all, chunk...)
-		if len(chunk) < pageSize {
-			break
+		return len(chunk), nil
+	})
+	return all, err
+}
+
+// paginate calls fetch for pages 1, 2, ... until one comes back short.
+func paginate(fetch func(page, pageSize int) (int, error)) error {
+	const pageSize = 100
+	for page := 1; ; page++ {
+		n, err := fetch(page, pageSize)
+		if err != nil {
+			return err
+		}
+		if n < pageSize {
+			return nil
 		}
-		page++
 	}
-	return all, nil
+}
+
+// queryParam returns "&q=<q>" for a non-empty Harbor query filter.
+func queryParam(q string) string {
+	if q == "" {
+		return ""
+	}
+	return "&q=" + url.QueryEscape(q)
 }
 
 func (c *Client) ListArtifacts(project, repo string) ([]Artifact, error) {
+	return c.ListArtifactsQuery(project, repo, "")
+}
+
+// ListArtifactsQuery is ListArtifacts with a Harbor q filter.
+func (c *Client) ListArtifactsQuery(project, repo, q string) ([]Artifact, error) {
 	const pageSize = 100
 
 	// helpers
@@ -116,23 +424,23 @@ func (c *Client) ListArtifacts(project, repo string) ([]Artifact, error) {
 
 	// Build the 4 candidate URLs in order
 	// 1) project-scoped, single-encoded repository_name
-	u1 := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts?page=1&page_size=%d&with_tag=true",
+	u1 := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts?page=1&page_size=%d&with_tag=true&with_label=true",
 		c.Base, url.PathEscape(project), enc1(repo), pageSize)
 
 	// 2) global, single-encoded full name "project/repo"
 	full := project + "/" + repo
-	u2 := fmt.Sprintf("%s/api/v2.0/repositories/%s/artifacts?page=1&page_size=%d&with_tag=true",
+	u2 := fmt.Sprintf("%s/api/v2.0/repositories/%s/artifacts?page=1&page_size=%d&with_tag=true&with_label=true",
 		c.Base, enc1(full), pageSize)
 
 	// 3) project-scoped, double-encoded repository_name
-	u3 := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts?page=1&page_size=%d&with_tag=true",
+	u3 := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts?page=1&page_size=%d&with_tag=true&with_label=true",
 		c.Base, url.PathEscape(project), enc2(repo), pageSize)
 
 	// 4) global, double-encoded full name
-	u4 := fmt.Sprintf("%s/api/v2.0/repositories/%s/artifacts?page=1&page_size=%d&with_tag=true",
+	u4 := fmt.Sprintf("%s/api/v2.0/repositories/%s/artifacts?page=1&page_size=%d&with_tag=true&with_label=true",
 		c.Base, enc2(full), pageSize)
 
-	candidates := []string{u1, u2, u3, u4}
+	candidates := []string{u1 + queryParam(q), u2 + queryParam(q), u3 + queryParam(q), u4 + queryParam(q)}
 	var lastErr error
 	var all []Artifact
 
@@ -145,7 +453,10 @@ func (c *Client) ListArtifacts(project, repo string) ([]Artifact, error) {
 			// replace the page query in-place
 			pageURL := strings.Replace(u, "page=1", fmt.Sprintf("page=%d", page), 1)
 			if err := c.getJSON(pageURL, &chunk); err != nil {
-				lastErr = err
+				// a candidate Harbor lacks (404) must not hide a real failure
+				if lastErr == nil || IsNotFound(lastErr) {
+					lastErr = err
+				}
 				all = nil
 				break
 			}
@@ -163,3 +474,73 @@ func (c *Client) ListArtifacts(project, repo string) ([]Artifact, error) {
 	}
 	return all, nil
 }
+
+// artifactURL is the API URL of an artifact (digest or tag) of project/repo.
+// Harbor wants slashes in repository names double-encoded here.
+func (c *Client) artifactURL(project, repo, reference string) string {
+	return fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts/%s",
+		c.Base, url.PathEscape(project), url.PathEscape(url.PathEscape(repo)), url.PathEscape(reference))
+}
+
+// DeleteTag removes tag from the artifact reference (digest or tag); the
+// artifact itself stays.
+func (c *Client) DeleteTag(project, repo, reference, tag string) error {
+	return c.delete(c.artifactURL(project, repo, reference) + "/tags/" + url.PathEscape(tag))
+}
+
+// DeleteArtifact removes the artifact reference (digest or tag) with all its
+// tags. Storage is reclaimed by Harbor's garbage collection.
+func (c *Client) DeleteArtifact(project, repo, reference string) error {
+	return c.delete(c.artifactURL(project, repo, reference))
+}
+
+// HasChartRepo reports whether Harbor serves the classic chart repository
+// API (ChartMuseum), which Harbor 2.8 removed.
+func (c *Client) HasChartRepo() (bool, error) {
+	var health map[string]any
+	err := c.getJSON(c.Base+"/api/chartrepo/health", &health)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// UploadChart pushes a classic chart .tgz into the project's chart repository
+// (Harbor's ChartMuseum API).
+func (c *Client) UploadChart(project, chartPath string) error {
+	f, err := os.Open(chartPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("chart", filepath.Base(chartPath))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/api/chartrepo/%s/charts", c.Base, url.PathEscape(project))
+	req, _ := http.NewRequest(http.MethodPost, u, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if c.User != "" || c.Pass != "" {
+		req.SetBasicAuth(c.User, c.Pass)
+	}
+	resp, err := c.httpc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("harbor POST %s: status %s %s", u, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FailedTask is a task that failed, with where it was going and why.
+type FailedTask struct {
+	Task
+	To    string `json:"to"`    // destination registry name in config.yaml
+	Class string `json:"class"` // error class (network, server, rate-limit, auth, ...)
+	Error string `json:"error"`
+}
+
+// FailedFile is the machine-readable list of failed tasks written after a
+// sync and read by `harair retry`.
+type FailedFile struct {
+	Version int          `json:"version"`
+	Created time.Time    `json:"created"`
+	Tasks   []FailedTask `json:"tasks"`
+}
+
+// WriteFailed writes tasks to path, replacing it atomically.
+func WriteFailed(path string, tasks []FailedTask) error {
+	if tasks == nil {
+		tasks = []FailedTask{}
+	}
+	b, err := json.MarshalIndent(FailedFile{Version: Version, Created: time.Now().UTC(), Tasks: tasks}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadFailed loads a file written by WriteFailed.
+func ReadFailed(path string) (*FailedFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f FailedFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if f.Version == 0 || f.Version > Version {
+		return nil, fmt.Errorf("%s: unsupported failed-task file version %d", path, f.Version)
+	}
+	return &f, nil
+}
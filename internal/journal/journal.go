@@ -0,0 +1,235 @@
+// Package journal records the tasks of a sync run and the state of each one
+// in an append-only JSON-lines file, so an interrupted run can be resumed.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Version is the journal format version written in the header.
+const Version = 1
+
+// Task kinds.
+const (
+	KindCopy      = "copy"      // image or OCI chart copy
+	KindChartRepo = "chartrepo" // classic chart upload to the chart repository
+)
+
+// State of a task.
+type State string
+
+const (
+	Pending State = "pending"
+	Done    State = "done"
+	Skipped State = "skipped" // missing on source
+	Failed  State = "failed"
+)
+
+// Header is the first line of a journal.
+type Header struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	To      string    `json:"to"` // destination registry name in config.yaml
+}
+
+// Task is one planned unit of work. Digest is what the source tag resolved
+// to at planning time; a resumed run copies that digest, not the tag.
+type Task struct {
+	ID      int    `json:"id"`
+	Kind    string `json:"kind"`
+	From    string `json:"from"` // source registry name in config.yaml
+	Project string `json:"project"`
+	Repo    string `json:"repo"`
+	Tag     string `json:"tag"`
+	Digest  string `json:"digest,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	SrcRef  string `json:"src"`
+	DstRef  string `json:"dst"`
+	// Platforms to copy from an index (os/arch[/variant]); empty for all.
+	Platforms []string `json:"platforms,omitempty"`
+}
+
+// line is one JSON line: a header, a task, or a state change.
+type line struct {
+	Header *Header    `json:"header,omitempty"`
+	Task   *Task      `json:"task,omitempty"`
+	ID     int        `json:"id,omitempty"`
+	State  State      `json:"state,omitempty"`
+	Class  string     `json:"class,omitempty"`
+	Error  string     `json:"error,omitempty"`
+	Time   *time.Time `json:"time,omitempty"`
+}
+
+// Journal is an open journal file. Set is safe for concurrent use.
+type Journal struct {
+	Path   string
+	Header Header
+	Tasks  []Task
+
+	mu     sync.Mutex
+	f      *os.File
+	states map[int]State
+	errs   map[int]string
+	class  map[int]string
+}
+
+// Create starts a new journal at path for a run syncing to registry to.
+func Create(path, to string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		Path:   path,
+		Header: Header{Version: Version, Created: time.Now().UTC(), To: to},
+		f:      f,
+		states: map[int]State{},
+		errs:   map[int]string{},
+		class:  map[int]string{},
+	}
+	if err := j.write(line{Header: &j.Header}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// Open loads an existing journal and reopens it for appending. A truncated
+// last line (the process died mid-write) is dropped.
+func Open(path string) (*Journal, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	j := &Journal{Path: path, states: map[int]State{}, errs: map[int]string{}, class: map[int]string{}}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var pendingErr error
+	var good int64 // end of the last valid line
+	for n := 1; sc.Scan(); n++ {
+		if pendingErr != nil {
+			return nil, pendingErr
+		}
+		var l line
+		if err := json.Unmarshal(sc.Bytes(), &l); err != nil {
+			pendingErr = fmt.Errorf("%s:%d: %w", path, n, err)
+			continue
+		}
+		good += int64(len(sc.Bytes())) + 1
+		switch {
+		case l.Header != nil:
+			j.Header = *l.Header
+		case l.Task != nil:
+			j.Tasks = append(j.Tasks, *l.Task)
+			j.states[l.Task.ID] = Pending
+		case l.ID != 0:
+			j.states[l.ID] = l.State
+			j.errs[l.ID] = l.Error
+			j.class[l.ID] = l.Class
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if j.Header.Version == 0 {
+		return nil, fmt.Errorf("%s: not a harair journal", path)
+	}
+	if j.Header.Version > Version {
+		return nil, fmt.Errorf("%s: journal version %d is newer than supported (%d)", path, j.Header.Version, Version)
+	}
+
+	if j.f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600); err != nil {
+		return nil, err
+	}
+	if pendingErr != nil {
+		if err := j.f.Truncate(good); err != nil {
+			j.f.Close()
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// Add records tasks as pending, assigning their IDs, and returns them.
+func (j *Journal) Add(tasks ...Task) ([]Task, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range tasks {
+		tasks[i].ID = len(j.Tasks) + 1
+		if err := j.write(line{Task: &tasks[i]}); err != nil {
+			return nil, err
+		}
+		j.Tasks = append(j.Tasks, tasks[i])
+		j.states[tasks[i].ID] = Pending
+	}
+	return tasks, nil
+}
+
+// Set records the new state of task id; err and its class are stored for
+// failed tasks.
+func (j *Journal) Set(id int, state State, class string, err error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now().UTC()
+	l := line{ID: id, State: state, Time: &now}
+	if err != nil {
+		l.Class, l.Error = class, err.Error()
+	}
+	j.states[id] = state
+	j.errs[id] = l.Error
+	j.class[id] = l.Class
+	return j.write(l)
+}
+
+// State returns the current state of task id and its last error class and
+// message.
+func (j *Journal) State(id int) (state State, class, msg string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.states[id], j.class[id], j.errs[id]
+}
+
+// Unfinished returns the tasks that are pending or failed, in plan order.
+func (j *Journal) Unfinished() []Task {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var out []Task
+	for _, t := range j.Tasks {
+		if s := j.states[t.ID]; s == Pending || s == Failed {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func (j *Journal) Close() error {
+	if j.f == nil {
+		return nil
+	}
+	return j.f.Close()
+}
+
+// write appends one line; callers hold j.mu (or own j exclusively).
+func (j *Journal) write(l line) error {
+	if j.f == nil {
+		return errors.New("journal is not open")
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	_, err = j.f.Write(append(b, '\n'))
+	return err
+}
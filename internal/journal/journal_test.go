@@ -0,0 +1,123 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newJournal creates a journal with three tasks: done, failed and pending.
+func newJournal(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sync.jsonl")
+	j, err := Create(path, "harbor2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	tasks, err := j.Add(
+		Task{Kind: KindCopy, From: "harbor1", Project: "p", Repo: "a", Tag: "v1", Digest: "sha256:a"},
+		Task{Kind: KindCopy, From: "harbor1", Project: "p", Repo: "b", Tag: "v1", Digest: "sha256:b", Platforms: []string{"linux/amd64"}},
+		Task{Kind: KindChartRepo, From: "harbor1", Project: "charts", Repo: "app", Tag: "1.0.0"},
+	)
+	if err != nil || tasks[2].ID != 3 {
+		t.Fatalf("Add = %+v %v", tasks, err)
+	}
+	j.Set(1, Done, "", nil)
+	j.Set(2, Failed, "server", errors.New("503 Service Unavailable"))
+	return path
+}
+
+func TestOpenResume(t *testing.T) {
+	path := newJournal(t)
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Header.To != "harbor2" || j.Header.Version != Version || len(j.Tasks) != 3 || j.Tasks[1].Platforms[0] != "linux/amd64" {
+		t.Fatalf("reopened %+v", j)
+	}
+	for id, want := range map[int][3]string{
+		1: {string(Done), "", ""},
+		2: {string(Failed), "server", "503 Service Unavailable"},
+		3: {string(Pending), "", ""},
+	} {
+		if state, class, msg := j.State(id); string(state) != want[0] || class != want[1] || msg != want[2] {
+			t.Errorf("task %d: %s %q %q, want %v", id, state, class, msg, want)
+		}
+	}
+	if todo := j.Unfinished(); len(todo) != 2 || todo[0].ID != 2 || todo[1].ID != 3 {
+		t.Errorf("unfinished %+v", todo)
+	}
+
+	// the resumed run appends to the same file
+	j.Set(2, Done, "", nil)
+	j.Set(3, Skipped, "not-found", errors.New("manifest unknown"))
+	j.Close()
+	j, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if state, class, msg := j.State(2); state != Done || class != "" || msg != "" {
+		t.Errorf("task 2 after resume: %s %q %q", state, class, msg)
+	}
+	if state, _, _ := j.State(3); state != Skipped {
+		t.Errorf("task 3 after resume: %s", state)
+	}
+	if todo := j.Unfinished(); len(todo) != 0 {
+		t.Errorf("unfinished after resume %+v", todo)
+	}
+}
+
+func TestOpenTruncated(t *testing.T) {
+	path := newJournal(t)
+	good, _ := os.ReadFile(path)
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	f.WriteString(`{"id":3,"state":"do`) // died mid-write
+	f.Close()
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != string(good) {
+		t.Errorf("bad trailing line kept:\n%s", b)
+	}
+	if state, _, _ := j.State(3); state != Pending {
+		t.Errorf("task 3: %s, want pending", state)
+	}
+	j.Set(3, Done, "", nil)
+	j.Close()
+	if j, err = Open(path); err != nil {
+		t.Fatalf("after truncating and appending: %v", err)
+	}
+	defer j.Close()
+	if state, _, _ := j.State(3); state != Done {
+		t.Errorf("task 3: %s, want done", state)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := newJournal(t)
+	b, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(b), "\n")
+	for name, tc := range map[string]struct{ content, want string }{
+		"bad line mid-file": {lines[0] + "{oops\n" + strings.Join(lines[1:], ""), "sync.jsonl:2"},
+		"no header":         {strings.Join(lines[1:], ""), "not a harair journal"},
+		"newer version":     {`{"header":{"version":99,"to":"h"}}` + "\n", "newer than supported"},
+	} {
+		os.WriteFile(path, []byte(tc.content), 0o600)
+		if j, err := Open(path); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err %v, want %q", name, err, tc.want)
+			if j != nil {
+				j.Close()
+			}
+		}
+	}
+	if _, err := Create(path, "h"); err == nil {
+		t.Error("Create over an existing journal: no error")
+	}
+}
@@ -0,0 +1,134 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+)
+
+// BlobAction says how copyBlob got a blob to the destination.
+type BlobAction string
+
+const (
+	BlobExists  BlobAction = "exists"
+	BlobMounted BlobAction = "mounted"
+	BlobCopied  BlobAction = "copied"
+	BlobSkipped BlobAction = "skipped" // foreign layer, left to its URLs
+)
+
+// CopyOptions tunes Copy.
+type CopyOptions struct {
+	// MountFrom names the source repository when source and destination
+	// are on the same registry, enabling cross-repository blob mounts.
+	MountFrom string
+	// OnBlob, if set, is called after each blob is handled.
+	OnBlob func(d Descriptor, action BlobAction)
+	// Platforms, if set, limits an index to the matching child manifests
+	// (see FilterIndex); the destination gets the filtered index. Single
+	// images are copied as they are.
+	Platforms []Platform
+}
+
+// Copy copies the manifest srcRef (tag or digest) from src to dst as dstRef,
+// with everything it references: child manifests of an index, configs and
+// layers. It returns the descriptor of the copied root manifest, which
+// differs from the source when opts.Platforms filtered an index.
+func Copy(ctx context.Context, src, dst Target, srcRef, dstRef string, opts CopyOptions) (Descriptor, error) {
+	body, mediaType, err := src.Manifest(ctx, srcRef)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	if len(opts.Platforms) > 0 && IsIndex(mediaType) {
+		if body, _, err = FilterIndex(body, opts.Platforms); err != nil {
+			return Descriptor{}, fmt.Errorf("%s: %w", srcRef, err)
+		}
+		if IsDigest(dstRef) {
+			dstRef = Digest(body) // the filtered index has a digest of its own
+		}
+	}
+	d := Descriptor{MediaType: mediaType, Digest: Digest(body), Size: int64(len(body))}
+	if err := copyManifest(ctx, src, dst, d, body, dstRef, opts); err != nil {
+		return Descriptor{}, err
+	}
+	return d, nil
+}
+
+// copyManifest copies what manifest d references, then the manifest itself.
+func copyManifest(ctx context.Context, src, dst Target, d Descriptor, body []byte, dstRef string, opts CopyOptions) error {
+	m, err := ParseManifest(body, d.MediaType)
+	if err != nil {
+		return fmt.Errorf("parse manifest %s: %w", d.Digest, err)
+	}
+
+	if IsIndex(m.MediaType) {
+		for _, child := range m.Manifests {
+			cb, cmt, err := src.Manifest(ctx, child.Digest)
+			if err != nil {
+				return err
+			}
+			if child.MediaType == "" {
+				child.MediaType = cmt
+			}
+			if err := copyManifest(ctx, src, dst, child, cb, child.Digest, opts); err != nil {
+				return err
+			}
+		}
+	} else {
+		var blobs []Descriptor
+		if m.Config != nil {
+			blobs = append(blobs, *m.Config)
+		}
+		blobs = append(blobs, m.Layers...)
+		for _, b := range blobs {
+			action, err := copyBlob(ctx, src, dst, b, opts.MountFrom)
+			if err != nil {
+				return err
+			}
+			if opts.OnBlob != nil {
+				opts.OnBlob(b, action)
+			}
+		}
+	}
+
+	return dst.PutManifest(ctx, dstRef, body, m.MediaType)
+}
+
+func copyBlob(ctx context.Context, src, dst Target, d Descriptor, mountFrom string) (BlobAction, error) {
+	if len(d.URLs) > 0 {
+		return BlobSkipped, nil
+	}
+	ok, err := dst.HasBlob(ctx, d)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return BlobExists, nil
+	}
+	if m, canMount := dst.(Mounter); canMount && mountFrom != "" {
+		mounted, err := m.Mount(ctx, d, mountFrom)
+		if err != nil {
+			return "", err
+		}
+		if mounted {
+			return BlobMounted, nil
+		}
+	}
+
+	rc, err := src.Blob(ctx, d)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	if err := dst.PutBlob(ctx, d, rc); err != nil {
+		return "", err
+	}
+	return BlobCopied, nil
+}
+
+// Open returns the Target and reference for a parsed ref. client supplies
+// the registry client for docker refs (so callers control credentials).
+func Open(r Ref, client func(host string) *Client) (Target, string) {
+	if r.Transport == "oci" {
+		return &Layout{Path: r.Path}, r.Reference
+	}
+	return client(r.Host).Repository(r.Repo), r.Reference
+}
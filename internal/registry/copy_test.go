@@ -0,0 +1,166 @@
+package registry
+
+import (
+	"context"
+	"testing"
+)
+
+// recordBlobs returns an OnBlob hook counting actions.
+func recordBlobs(actions map[BlobAction]int) func(Descriptor, BlobAction) {
+	return func(_ Descriptor, a BlobAction) { actions[a]++ }
+}
+
+func TestCopyImage(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newTestRegistry(t), newTestRegistry(t)
+	want := src.pushImage(t, "team/app", "v1", "amd64")
+
+	actions := map[BlobAction]int{}
+	got, err := Copy(ctx, src.Client("", "").Repository("team/app"), dst.Client("", "").Repository("mirror/app"),
+		"v1", "v1", CopyOptions{OnBlob: recordBlobs(actions)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Digest != want.Digest {
+		t.Errorf("digest = %s, want %s", got.Digest, want.Digest)
+	}
+	if body, ok := dst.hasManifest("mirror/app", "v1"); !ok || Digest(body) != want.Digest {
+		t.Errorf("destination tag v1 missing or different")
+	}
+	if actions[BlobCopied] != 3 {
+		t.Errorf("copied %d blobs, want 3 (%v)", actions[BlobCopied], actions)
+	}
+
+	// a second copy finds everything in place
+	actions = map[BlobAction]int{}
+	if _, err := Copy(ctx, src.Client("", "").Repository("team/app"), dst.Client("", "").Repository("mirror/app"),
+		"v1", "v1", CopyOptions{OnBlob: recordBlobs(actions)}); err != nil {
+		t.Fatal(err)
+	}
+	if actions[BlobExists] != 3 || dst.count("PUT blob") != 3 {
+		t.Errorf("second copy: actions %v, %d uploads; want 3 existing, 3 uploads in total", actions, dst.count("PUT blob"))
+	}
+}
+
+func TestCopyByDigest(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newTestRegistry(t), newTestRegistry(t)
+	want := src.pushImage(t, "app", "v1", "amd64")
+
+	if _, err := Copy(ctx, src.Client("", "").Repository("app"), dst.Client("", "").Repository("app"),
+		want.Digest, "v1", CopyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if body, ok := dst.hasManifest("app", "v1"); !ok || Digest(body) != want.Digest {
+		t.Errorf("pinned copy did not tag v1 with %s", want.Digest)
+	}
+}
+
+func TestCopyMissingSource(t *testing.T) {
+	src, dst := newTestRegistry(t), newTestRegistry(t)
+	_, err := Copy(context.Background(), src.Client("", "").Repository("app"), dst.Client("", "").Repository("app"),
+		"nope", "nope", CopyOptions{})
+	if !IsNotFound(err) {
+		t.Errorf("err = %v, want not found", err)
+	}
+}
+
+func TestCopyMount(t *testing.T) {
+	ctx := context.Background()
+	reg := newTestRegistry(t)
+	reg.pushImage(t, "a/src", "v1", "amd64")
+	c := reg.Client("", "")
+
+	actions := map[BlobAction]int{}
+	if _, err := Copy(ctx, c.Repository("a/src"), c.Repository("b/dst"), "v1", "v1",
+		CopyOptions{MountFrom: "a/src", OnBlob: recordBlobs(actions)}); err != nil {
+		t.Fatal(err)
+	}
+	if actions[BlobMounted] != 3 || reg.count("PUT blob") != 0 || reg.count("GET blob") != 0 {
+		t.Errorf("actions %v, %d uploads, %d downloads; want 3 mounts and no transfer",
+			actions, reg.count("PUT blob"), reg.count("GET blob"))
+	}
+	if _, ok := reg.hasManifest("b/dst", "v1"); !ok {
+		t.Error("destination manifest missing")
+	}
+}
+
+func TestCopyMountRefused(t *testing.T) {
+	ctx := context.Background()
+	reg := newTestRegistry(t)
+	reg.NoMount = true
+	reg.pushImage(t, "a/src", "v1", "amd64")
+	c := reg.Client("", "")
+
+	actions := map[BlobAction]int{}
+	if _, err := Copy(ctx, c.Repository("a/src"), c.Repository("b/dst"), "v1", "v1",
+		CopyOptions{MountFrom: "a/src", OnBlob: recordBlobs(actions)}); err != nil {
+		t.Fatal(err)
+	}
+	if actions[BlobCopied] != 3 || reg.count("mount") != 0 {
+		t.Errorf("actions %v; want 3 copied blobs after refused mounts", actions)
+	}
+	// every refused mount's upload session is abandoned
+	if n := reg.count("DELETE upload"); n != 3 {
+		t.Errorf("%d upload sessions cancelled, want 3", n)
+	}
+}
+
+func TestCopyIndex(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newTestRegistry(t), newTestRegistry(t)
+	want := src.pushIndex(t, "app", "v1", "linux/amd64", "linux/arm64/v8")
+
+	got, err := Copy(ctx, src.Client("", "").Repository("app"), dst.Client("", "").Repository("app"),
+		"v1", "v1", CopyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Digest != want.Digest || got.MediaType != MediaTypeOCIIndex {
+		t.Errorf("got %+v, want index %s", got, want.Digest)
+	}
+	body, _ := dst.hasManifest("app", "v1")
+	m, _ := ParseManifest(body, "")
+	for _, child := range m.Manifests {
+		if _, ok := dst.hasManifest("app", child.Digest); !ok {
+			t.Errorf("child %s (%s) not copied", child.Digest, child.Platform)
+		}
+	}
+	if dst.count("PUT blob") != 6 {
+		t.Errorf("%d blobs uploaded, want 6", dst.count("PUT blob"))
+	}
+}
+
+func TestCopyIndexPlatforms(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newTestRegistry(t), newTestRegistry(t)
+	src.pushIndex(t, "app", "v1", "linux/amd64", "linux/arm64/v8", "linux/s390x")
+	srcBody, _ := src.hasManifest("app", "v1")
+
+	platforms := []Platform{{OS: "linux", Architecture: "arm64"}}
+	got, err := Copy(ctx, src.Client("", "").Repository("app"), dst.Client("", "").Repository("app"),
+		"v1", "v1", CopyOptions{Platforms: platforms})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, kept, err := FilterIndex(srcBody, platforms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Digest != Digest(filtered) {
+		t.Errorf("digest %s, want the filtered index %s", got.Digest, Digest(filtered))
+	}
+	body, _ := dst.hasManifest("app", "v1")
+	m, _ := ParseManifest(body, "")
+	if len(m.Manifests) != 1 || m.Manifests[0].Digest != kept[0].Digest {
+		t.Errorf("destination index has %d children, want only arm64", len(m.Manifests))
+	}
+	if dst.count("PUT blob") != 3 {
+		t.Errorf("%d blobs uploaded, want 3 (one platform)", dst.count("PUT blob"))
+	}
+
+	if _, err := Copy(ctx, src.Client("", "").Repository("app"), dst.Client("", "").Repository("app"),
+		"v1", "v2", CopyOptions{Platforms: []Platform{{OS: "windows", Architecture: "amd64"}}}); err == nil {
+		t.Error("copy with no matching platform succeeded")
+	}
+}
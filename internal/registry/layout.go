@@ -0,0 +1,194 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const refNameAnnotation = "org.opencontainers.image.ref.name"
+
+// layoutLocks serializes index.json updates per layout directory.
+var layoutLocks sync.Map
+
+// Layout is a Target backed by an OCI image-layout directory. Tags are
+// ref.name annotations in index.json.
+type Layout struct {
+	Path string
+}
+
+func (l *Layout) blobPath(digest string) string {
+	algo, hexsum, _ := strings.Cut(digest, ":")
+	return filepath.Join(l.Path, "blobs", algo, hexsum)
+}
+
+func (l *Layout) lock() func() {
+	v, _ := layoutLocks.LoadOrStore(filepath.Clean(l.Path), &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+type layoutIndex struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+func (l *Layout) readIndex() (*layoutIndex, error) {
+	b, err := os.ReadFile(filepath.Join(l.Path, "index.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return &layoutIndex{SchemaVersion: 2, MediaType: MediaTypeOCIIndex}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var idx layoutIndex
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil, fmt.Errorf("parse %s/index.json: %w", l.Path, err)
+	}
+	return &idx, nil
+}
+
+func (l *Layout) Manifest(ctx context.Context, reference string) ([]byte, string, error) {
+	digest, mediaType := reference, ""
+	if !IsDigest(reference) {
+		idx, err := l.readIndex()
+		if err != nil {
+			return nil, "", err
+		}
+		found := false
+		for _, d := range idx.Manifests {
+			if d.Annotations[refNameAnnotation] == reference {
+				digest, mediaType, found = d.Digest, d.MediaType, true
+				break
+			}
+		}
+		if !found {
+			return nil, "", fmt.Errorf("%s:%s: %w", l.Path, reference, ErrNotFound)
+		}
+	}
+	b, err := os.ReadFile(l.blobPath(digest))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", fmt.Errorf("%s@%s: %w", l.Path, digest, ErrNotFound)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if Digest(b) != digest {
+		return nil, "", fmt.Errorf("%s@%s: manifest digest mismatch", l.Path, digest)
+	}
+	m, err := ParseManifest(b, mediaType)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, m.MediaType, nil
+}
+
+func (l *Layout) PutManifest(ctx context.Context, reference string, body []byte, mediaType string) error {
+	d := Descriptor{MediaType: mediaType, Digest: Digest(body), Size: int64(len(body))}
+	if err := l.writeBlob(d, strings.NewReader(string(body))); err != nil {
+		return err
+	}
+	if IsDigest(reference) {
+		return nil
+	}
+
+	unlock := l.lock()
+	defer unlock()
+	idx, err := l.readIndex()
+	if err != nil {
+		return err
+	}
+	kept := idx.Manifests[:0]
+	for _, m := range idx.Manifests {
+		if m.Annotations[refNameAnnotation] != reference {
+			kept = append(kept, m)
+		}
+	}
+	d.Annotations = map[string]string{refNameAnnotation: reference}
+	idx.Manifests = append(kept, d)
+
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(l.Path, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0o644); err != nil {
+		return err
+	}
+	tmp := filepath.Join(l.Path, "index.json.tmp")
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(l.Path, "index.json"))
+}
+
+func (l *Layout) HasBlob(ctx context.Context, d Descriptor) (bool, error) {
+	fi, err := os.Stat(l.blobPath(d.Digest))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return fi.Size() == d.Size, nil
+}
+
+func (l *Layout) Blob(ctx context.Context, d Descriptor) (io.ReadCloser, error) {
+	f, err := os.Open(l.blobPath(d.Digest))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s blob %s: %w", l.Path, d.Digest, ErrNotFound)
+	}
+	return f, err
+}
+
+func (l *Layout) PutBlob(ctx context.Context, d Descriptor, r io.Reader) error {
+	return l.writeBlob(d, r)
+}
+
+// writeBlob stores r under d's digest, verifying size and digest first.
+func (l *Layout) writeBlob(d Descriptor, r io.Reader) error {
+	algo, hexsum, ok := strings.Cut(d.Digest, ":")
+	if !ok || algo != "sha256" {
+		return fmt.Errorf("unsupported digest %q", d.Digest)
+	}
+	dir := filepath.Join(l.Path, "blobs", algo)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-"+hexsum[:12]+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if n != d.Size {
+		return fmt.Errorf("blob %s: got %d bytes, expected %d", d.Digest, n, d.Size)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != hexsum {
+		return fmt.Errorf("blob %s: digest mismatch (got sha256:%s)", d.Digest, got)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, hexsum))
+}
+
+// Digest returns the sha256 digest of b.
+func Digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,100 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLayoutRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newTestRegistry(t), newTestRegistry(t)
+	want := src.pushIndex(t, "team/app", "v1", "linux/amd64", "linux/arm64/v8")
+	layout := &Layout{Path: t.TempDir()}
+
+	// registry -> oci: layout
+	if _, err := Copy(ctx, src.Client("", "").Repository("team/app"), layout, "v1", "team/app:v1", CopyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(layout.Path, "oci-layout")); err != nil {
+		t.Error("oci-layout file missing")
+	}
+	b, err := os.ReadFile(filepath.Join(layout.Path, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idx layoutIndex
+	if err := json.Unmarshal(b, &idx); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Manifests) != 1 || idx.Manifests[0].Digest != want.Digest ||
+		idx.Manifests[0].Annotations[refNameAnnotation] != "team/app:v1" {
+		t.Errorf("index.json = %s", b)
+	}
+
+	// oci: layout -> registry
+	got, err := Copy(ctx, layout, dst.Client("", "").Repository("app"), "team/app:v1", "v1", CopyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Digest != want.Digest {
+		t.Errorf("digest after round trip %s, want %s", got.Digest, want.Digest)
+	}
+	if dst.count("PUT blob") != 6 {
+		t.Errorf("%d blobs pushed from the layout, want 6", dst.count("PUT blob"))
+	}
+}
+
+func TestLayoutRetag(t *testing.T) {
+	ctx := context.Background()
+	layout := &Layout{Path: t.TempDir()}
+	a, b := []byte(`{"schemaVersion":2,"mediaType":"`+MediaTypeOCIManifest+`","layers":[]}`), []byte(`{"schemaVersion":2,"mediaType":"`+MediaTypeOCIManifest+`","layers":null}`)
+	if err := layout.PutManifest(ctx, "x", a, MediaTypeOCIManifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := layout.PutManifest(ctx, "x", b, MediaTypeOCIManifest); err != nil {
+		t.Fatal(err)
+	}
+	got, mt, err := layout.Manifest(ctx, "x")
+	if err != nil || string(got) != string(b) || mt != MediaTypeOCIManifest {
+		t.Errorf("got %s %s %v, want the second manifest", got, mt, err)
+	}
+	if idx, _ := layout.readIndex(); len(idx.Manifests) != 1 {
+		t.Errorf("index has %d entries for one name, want 1", len(idx.Manifests))
+	}
+	if _, _, err := layout.Manifest(ctx, "missing"); !IsNotFound(err) {
+		t.Errorf("missing name: err = %v, want not found", err)
+	}
+}
+
+func TestLayoutRejectsBadBlob(t *testing.T) {
+	layout := &Layout{Path: t.TempDir()}
+	d := Descriptor{Digest: Digest([]byte("right")), Size: 5}
+	err := layout.PutBlob(context.Background(), d, strings.NewReader("wrong"))
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("err = %v, want digest mismatch", err)
+	}
+	if ok, _ := layout.HasBlob(context.Background(), d); ok {
+		t.Error("bad blob was stored")
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"docker://h:5000/a/b:v1", "docker://h:5000/a/b:v1"},
+		{"docker://h/a/b", "docker://h/a/b:latest"},
+		{"docker://h/a/b@sha256:abc", "docker://h/a/b@sha256:abc"},
+		{"oci:/tmp/x:team/app:v1", "oci:/tmp/x:team/app:v1"},
+	} {
+		r, err := ParseRef(tc.in)
+		if err != nil || r.String() != tc.want {
+			t.Errorf("ParseRef(%q) = %q, %v; want %q", tc.in, r.String(), err, tc.want)
+		}
+	}
+	if _, err := ParseRef("http://h/a"); err == nil {
+		t.Error("unsupported transport accepted")
+	}
+}
@@ -0,0 +1,119 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// String formats p as os/arch[/variant].
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// ParsePlatform parses os/arch[/variant], e.g. linux/arm64/v8.
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q: want os/arch[/variant]", s)
+	}
+	p := Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// ParsePlatforms parses a list of platforms; nil means all of them.
+func ParsePlatforms(list []string) ([]Platform, error) {
+	var out []Platform
+	for _, s := range list {
+		p, err := ParsePlatform(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Matches reports whether other is p; a p without variant matches any variant.
+func (p Platform) Matches(other *Platform) bool {
+	if other == nil {
+		return false
+	}
+	return p.OS == other.OS && p.Architecture == other.Architecture &&
+		(p.Variant == "" || p.Variant == other.Variant)
+}
+
+// FilterIndex returns the index body with only the child manifests whose
+// platform matches one of platforms, and the kept children. Other fields are
+// preserved. The result is deterministic, so filtering the same index twice
+// gives the same digest. When every child is kept, body is returned as is.
+func FilterIndex(body []byte, platforms []Platform) ([]byte, []Descriptor, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, nil, err
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(doc["manifests"], &raw); err != nil {
+		return nil, nil, fmt.Errorf("index manifests: %w", err)
+	}
+
+	var keptRaw []json.RawMessage
+	var kept, all []Descriptor
+	for _, r := range raw {
+		var d Descriptor
+		if err := json.Unmarshal(r, &d); err != nil {
+			return nil, nil, err
+		}
+		all = append(all, d)
+		for _, p := range platforms {
+			if p.Matches(d.Platform) {
+				keptRaw = append(keptRaw, r)
+				kept = append(kept, d)
+				break
+			}
+		}
+	}
+	if len(kept) == 0 {
+		return nil, nil, fmt.Errorf("no manifest for %s in index (has %s)", platformList(platforms), descriptorPlatforms(all))
+	}
+	if len(kept) == len(all) {
+		return body, kept, nil
+	}
+	b, err := json.Marshal(keptRaw)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc["manifests"] = b
+	if body, err = json.Marshal(doc); err != nil {
+		return nil, nil, err
+	}
+	return body, kept, nil
+}
+
+func platformList(ps []Platform) string {
+	s := make([]string, len(ps))
+	for i, p := range ps {
+		s[i] = p.String()
+	}
+	return strings.Join(s, ",")
+}
+
+func descriptorPlatforms(ds []Descriptor) string {
+	var s []string
+	for _, d := range ds {
+		if d.Platform != nil {
+			s = append(s, d.Platform.String())
+		}
+	}
+	if len(s) == 0 {
+		return "no platforms"
+	}
+	return strings.Join(s, ",")
+}
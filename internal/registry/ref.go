@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ref is a parsed image reference in one of the transports harair uses:
+//
+//	docker://host/repo:tag
+//	docker://host/repo@sha256:...
+//	oci:/path/to/layout:name
+type Ref struct {
+	Transport string // "docker" or "oci"
+	Host      string // docker: registry host[:port]
+	Repo      string // docker: repository path
+	Path      string // oci: layout directory
+	Reference string // tag, digest, or (oci) ref.name
+}
+
+func ParseRef(s string) (Ref, error) {
+	if rest, ok := strings.CutPrefix(s, "docker://"); ok {
+		host, path, ok := strings.Cut(rest, "/")
+		if !ok || host == "" || path == "" {
+			return Ref{}, fmt.Errorf("invalid ref %q: want docker://host/repo:tag", s)
+		}
+		r := Ref{Transport: "docker", Host: host}
+		if repo, dgst, ok := strings.Cut(path, "@"); ok {
+			r.Repo, r.Reference = repo, dgst
+			return r, nil
+		}
+		// the tag separator is the last ':' after the last '/'
+		slash := strings.LastIndex(path, "/")
+		if colon := strings.LastIndex(path, ":"); colon > slash {
+			r.Repo, r.Reference = path[:colon], path[colon+1:]
+		} else {
+			r.Repo, r.Reference = path, "latest"
+		}
+		return r, nil
+	}
+	if rest, ok := strings.CutPrefix(s, "oci:"); ok {
+		dir, name, _ := strings.Cut(rest, ":")
+		if dir == "" {
+			return Ref{}, fmt.Errorf("invalid ref %q: want oci:/path:name", s)
+		}
+		return Ref{Transport: "oci", Path: dir, Reference: name}, nil
+	}
+	return Ref{}, fmt.Errorf("unsupported transport in %q (want docker:// or oci:)", s)
+}
+
+func (r Ref) String() string {
+	if r.Transport == "oci" {
+		return "oci:" + r.Path + ":" + r.Reference
+	}
+	if IsDigest(r.Reference) {
+		return "docker://" + r.Host + "/" + r.Repo + "@" + r.Reference
+	}
+	return "docker://" + r.Host + "/" + r.Repo + ":" + r.Reference
+}
+
+// IsDigest reports whether reference is a digest rather than a tag.
+func IsDigest(reference string) bool {
+	return strings.HasPrefix(reference, "sha256:")
+}
@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Media types the copy engine understands.
+const (
+	MediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// manifestAccept is sent as Accept when fetching manifests.
+var manifestAccept = []string{MediaTypeOCIIndex, MediaTypeDockerList, MediaTypeOCIManifest, MediaTypeDockerManifest}
+
+// ErrNotFound means the manifest or blob does not exist.
+var ErrNotFound = errors.New("manifest unknown")
+
+// Descriptor is an OCI content descriptor.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	URLs        []string          `json:"urls,omitempty"`
+	Platform    *Platform         `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// Manifest is the union of image manifests and indexes we need to walk.
+type Manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    *Descriptor  `json:"config,omitempty"`
+	Layers    []Descriptor `json:"layers,omitempty"`
+	Manifests []Descriptor `json:"manifests,omitempty"`
+}
+
+// IsIndex reports whether mediaType is a multi-platform index / manifest list.
+func IsIndex(mediaType string) bool {
+	return mediaType == MediaTypeOCIIndex || mediaType == MediaTypeDockerList
+}
+
+// IsManifest reports whether mediaType is any manifest type we can copy.
+func IsManifest(mediaType string) bool {
+	return IsIndex(mediaType) || mediaType == MediaTypeOCIManifest || mediaType == MediaTypeDockerManifest
+}
+
+// ParseManifest decodes b; mediaType overrides a missing mediaType field.
+func ParseManifest(b []byte, mediaType string) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	if m.MediaType == "" {
+		m.MediaType = mediaType
+	}
+	if m.MediaType == "" {
+		// OCI manifests may omit mediaType; tell them apart by shape
+		if m.Manifests != nil {
+			m.MediaType = MediaTypeOCIIndex
+		} else {
+			m.MediaType = MediaTypeOCIManifest
+		}
+	}
+	return &m, nil
+}
+
+// Target is somewhere images can be read from and written to: a remote
+// repository or a local OCI layout.
+type Target interface {
+	// Manifest returns the manifest for a tag or digest with its media type.
+	Manifest(ctx context.Context, reference string) ([]byte, string, error)
+	// PutManifest stores a manifest under a tag or its digest.
+	PutManifest(ctx context.Context, reference string, body []byte, mediaType string) error
+	HasBlob(ctx context.Context, d Descriptor) (bool, error)
+	Blob(ctx context.Context, d Descriptor) (io.ReadCloser, error)
+	PutBlob(ctx context.Context, d Descriptor, r io.Reader) error
+}
+
+// Mounter is implemented by targets that can link a blob from another
+// repository on the same registry without transferring it.
+type Mounter interface {
+	// Mount reports whether the blob was mounted; false means it was not
+	// (and the caller should upload it).
+	Mount(ctx context.Context, d Descriptor, fromRepo string) (bool, error)
+}
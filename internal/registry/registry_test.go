@@ -0,0 +1,357 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testRegistry is a small in-process Distribution v2 registry: enough of
+// manifests, blobs, uploads, cross-repo mounts and token auth for Copy.
+type testRegistry struct {
+	*httptest.Server
+
+	// NoMount makes the registry decline mounts with an upload session.
+	NoMount bool
+	// User and Pass, when set, require a bearer token from /token, which
+	// takes those as basic auth. Basic makes it ask for basic auth instead.
+	User, Pass string
+	Basic      bool
+
+	mu        sync.Mutex
+	blobs     map[string][]byte          // digest -> content
+	repoBlobs map[string]map[string]bool // repo -> digests linked to it
+	manifests map[string]map[string]testManifest
+	uploads   map[string]string // upload id -> repo
+	scopes    []string          // scopes asked for at /token
+	calls     map[string]int    // "POST upload", "PUT blob", "mount", "DELETE upload", ...
+}
+
+type testManifest struct {
+	body      []byte
+	mediaType string
+}
+
+const testToken = "t0ken"
+
+var (
+	manifestPath = regexp.MustCompile(`^/v2/(.+)/manifests/([^/]+)$`)
+	blobPath     = regexp.MustCompile(`^/v2/(.+)/blobs/(sha256:[a-f0-9]+)$`)
+	uploadPath   = regexp.MustCompile(`^/v2/(.+)/blobs/uploads/([^/]*)$`)
+)
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	t.Helper()
+	r := &testRegistry{
+		blobs:     map[string][]byte{},
+		repoBlobs: map[string]map[string]bool{},
+		manifests: map[string]map[string]testManifest{},
+		uploads:   map[string]string{},
+		calls:     map[string]int{},
+	}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// Host is the host:port to give NewClient.
+func (r *testRegistry) Host() string {
+	u, _ := url.Parse(r.URL)
+	return u.Host
+}
+
+// Client returns an insecure client (plain http) for the registry.
+func (r *testRegistry) Client(user, pass string) *Client {
+	return NewClient(r.Host(), user, pass, true)
+}
+
+func (r *testRegistry) tokenScopes() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.scopes...)
+}
+
+func (r *testRegistry) count(call string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[call]
+}
+
+// putBlob stores b in repo and returns its descriptor.
+func (r *testRegistry) putBlob(repo string, b []byte, mediaType string) Descriptor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := Descriptor{MediaType: mediaType, Digest: Digest(b), Size: int64(len(b))}
+	r.blobs[d.Digest] = b
+	r.link(repo, d.Digest)
+	return d
+}
+
+// putManifest stores body in repo under its digest and tag (if any).
+func (r *testRegistry) putManifest(repo, tag string, body []byte, mediaType string) Descriptor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := Descriptor{MediaType: mediaType, Digest: Digest(body), Size: int64(len(body))}
+	r.storeManifest(repo, d.Digest, body, mediaType)
+	if tag != "" {
+		r.storeManifest(repo, tag, body, mediaType)
+	}
+	return d
+}
+
+func (r *testRegistry) hasManifest(repo, ref string) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.manifests[repo][ref]
+	return m.body, ok
+}
+
+func (r *testRegistry) hasBlob(repo, digest string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.repoBlobs[repo][digest]
+}
+
+// dropBlob unlinks a blob from repo, to simulate missing content.
+func (r *testRegistry) dropBlob(repo, digest string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.repoBlobs[repo], digest)
+}
+
+func (r *testRegistry) link(repo, digest string) {
+	if r.repoBlobs[repo] == nil {
+		r.repoBlobs[repo] = map[string]bool{}
+	}
+	r.repoBlobs[repo][digest] = true
+}
+
+func (r *testRegistry) storeManifest(repo, ref string, body []byte, mediaType string) {
+	if r.manifests[repo] == nil {
+		r.manifests[repo] = map[string]testManifest{}
+	}
+	r.manifests[repo][ref] = testManifest{body: body, mediaType: mediaType}
+}
+
+func (r *testRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		r.serveToken(w, req)
+		return
+	}
+	if !r.authorized(w, req) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := req.URL.Path
+	if p == "/v2/" {
+		return
+	}
+	if m := uploadPath.FindStringSubmatch(p); m != nil {
+		r.serveUpload(w, req, m[1], m[2])
+		return
+	}
+	if m := manifestPath.FindStringSubmatch(p); m != nil {
+		r.serveManifest(w, req, m[1], m[2])
+		return
+	}
+	if m := blobPath.FindStringSubmatch(p); m != nil {
+		repo, digest := m[1], m[2]
+		if !r.repoBlobs[repo][digest] {
+			writeError(w, http.StatusNotFound, "BLOB_UNKNOWN")
+			return
+		}
+		b := r.blobs[digest]
+		w.Header().Set("Content-Length", fmt.Sprint(len(b)))
+		if req.Method == http.MethodGet {
+			r.calls["GET blob"]++
+			w.Write(b)
+		}
+		return
+	}
+	writeError(w, http.StatusNotFound, "NAME_UNKNOWN")
+}
+
+func (r *testRegistry) authorized(w http.ResponseWriter, req *http.Request) bool {
+	switch {
+	case r.User == "":
+		return true
+	case r.Basic:
+		if u, p, ok := req.BasicAuth(); ok && u == r.User && p == r.Pass {
+			return true
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+	default:
+		if req.Header.Get("Authorization") == "Bearer "+testToken {
+			return true
+		}
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, r.URL))
+	}
+	writeError(w, http.StatusUnauthorized, "UNAUTHORIZED")
+	return false
+}
+
+func (r *testRegistry) serveToken(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	r.scopes = append(r.scopes, req.URL.Query()["scope"]...)
+	r.mu.Unlock()
+	if u, p, ok := req.BasicAuth(); !ok || u != r.User || p != r.Pass {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"token": testToken})
+}
+
+func (r *testRegistry) serveManifest(w http.ResponseWriter, req *http.Request, repo, ref string) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		m, ok := r.manifests[repo][ref]
+		if !ok {
+			writeError(w, http.StatusNotFound, "MANIFEST_UNKNOWN")
+			return
+		}
+		w.Header().Set("Content-Type", m.mediaType)
+		w.Header().Set("Docker-Content-Digest", Digest(m.body))
+		w.Header().Set("Content-Length", fmt.Sprint(len(m.body)))
+		if req.Method == http.MethodGet {
+			w.Write(m.body)
+		}
+	case http.MethodPut:
+		body, _ := io.ReadAll(req.Body)
+		mt := req.Header.Get("Content-Type")
+		m, err := ParseManifest(body, mt)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "MANIFEST_INVALID")
+			return
+		}
+		// like a real registry, refuse manifests whose content is missing
+		var refs []Descriptor
+		if m.Config != nil {
+			refs = append(refs, *m.Config)
+		}
+		refs = append(refs, m.Layers...)
+		for _, d := range refs {
+			if len(d.URLs) == 0 && !r.repoBlobs[repo][d.Digest] {
+				writeError(w, http.StatusBadRequest, "BLOB_UNKNOWN")
+				return
+			}
+		}
+		for _, d := range m.Manifests {
+			if _, ok := r.manifests[repo][d.Digest]; !ok {
+				writeError(w, http.StatusBadRequest, "MANIFEST_BLOB_UNKNOWN")
+				return
+			}
+		}
+		if IsDigest(ref) && Digest(body) != ref {
+			writeError(w, http.StatusBadRequest, "DIGEST_INVALID")
+			return
+		}
+		r.storeManifest(repo, Digest(body), body, mt)
+		r.storeManifest(repo, ref, body, mt)
+		r.calls["PUT manifest"]++
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *testRegistry) serveUpload(w http.ResponseWriter, req *http.Request, repo, id string) {
+	q := req.URL.Query()
+	switch req.Method {
+	case http.MethodPost:
+		r.calls["POST upload"]++
+		if from, digest := q.Get("from"), q.Get("mount"); from != "" {
+			if !r.NoMount && r.repoBlobs[from][digest] {
+				r.calls["mount"]++
+				r.link(repo, digest)
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+		}
+		id := fmt.Sprintf("u%d", len(r.uploads)+1)
+		r.uploads[id] = repo
+		w.Header().Set("Location", "/v2/"+repo+"/blobs/uploads/"+id)
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPut:
+		if r.uploads[id] != repo {
+			writeError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN")
+			return
+		}
+		body, _ := io.ReadAll(req.Body)
+		if Digest(body) != q.Get("digest") {
+			writeError(w, http.StatusBadRequest, "DIGEST_INVALID")
+			return
+		}
+		delete(r.uploads, id)
+		r.blobs[Digest(body)] = body
+		r.link(repo, Digest(body))
+		r.calls["PUT blob"]++
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		delete(r.uploads, id)
+		r.calls["DELETE upload"]++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"errors":[{"code":%q,"message":%q}]}`, code, strings.ToLower(code))
+}
+
+// pushImage stores a single-platform image (config and two layers) in repo
+// and returns its manifest descriptor.
+func (r *testRegistry) pushImage(t *testing.T, repo, tag, arch string) Descriptor {
+	t.Helper()
+	config := r.putBlob(repo, []byte(`{"architecture":"`+arch+`","os":"linux"}`), "application/vnd.oci.image.config.v1+json")
+	l1 := r.putBlob(repo, []byte("layer one "+arch), "application/vnd.oci.image.layer.v1.tar+gzip")
+	l2 := r.putBlob(repo, []byte("layer two "+arch), "application/vnd.oci.image.layer.v1.tar+gzip")
+	body := mustJSON(t, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeOCIManifest,
+		"config":        config,
+		"layers":        []Descriptor{l1, l2},
+	})
+	return r.putManifest(repo, tag, body, MediaTypeOCIManifest)
+}
+
+// pushIndex stores an index of one image per platform (os/arch[/variant]).
+func (r *testRegistry) pushIndex(t *testing.T, repo, tag string, platforms ...string) Descriptor {
+	t.Helper()
+	var children []Descriptor
+	for _, s := range platforms {
+		p, err := ParsePlatform(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := r.pushImage(t, repo, "", s)
+		d.Platform = &p
+		children = append(children, d)
+	}
+	body := mustJSON(t, map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     MediaTypeOCIIndex,
+		"manifests":     children,
+	})
+	return r.putManifest(repo, tag, body, MediaTypeOCIIndex)
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,478 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Client talks the Distribution v2 API to one registry host. It handles
+// basic auth and bearer token challenges, and is safe for concurrent use.
+type Client struct {
+	Host     string
+	User     string
+	Pass     string
+	Insecure bool // skip TLS verification, and fall back to plain http
+
+	httpc *http.Client
+
+	mu     sync.Mutex
+	scheme string            // "https" or "http", decided on first use
+	tokens map[string]string // scope -> bearer token
+	basic  bool              // registry asked for basic auth
+}
+
+func NewClient(host, user, pass string, insecure bool) *Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure {
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec
+	}
+	return &Client{
+		Host:     host,
+		User:     user,
+		Pass:     pass,
+		Insecure: insecure,
+		httpc:    &http.Client{Transport: tr},
+		tokens:   map[string]string{},
+	}
+}
+
+// Repository returns a Target for repo on this registry.
+func (c *Client) Repository(repo string) *Repository {
+	return &Repository{c: c, Name: repo}
+}
+
+// baseURL picks https, or http for insecure registries that don't speak TLS.
+func (c *Client) baseURL(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	scheme := c.scheme
+	c.mu.Unlock()
+	if scheme != "" {
+		return scheme + "://" + c.Host, nil
+	}
+
+	scheme = "https"
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+c.Host+"/v2/", nil)
+	resp, err := c.httpc.Do(req)
+	if err != nil {
+		if !c.Insecure {
+			return "", err
+		}
+		scheme = "http"
+	} else {
+		resp.Body.Close()
+	}
+	c.mu.Lock()
+	c.scheme = scheme
+	c.mu.Unlock()
+	return scheme + "://" + c.Host, nil
+}
+
+// do sends req with the credentials known for scope, answering one auth
+// challenge if needed. Requests whose body can't be replayed must be made
+// after another request on the same scope has obtained a token.
+func (c *Client) do(req *http.Request, scope string) (*http.Response, error) {
+	c.authorize(req, scope)
+	resp, err := c.httpc.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if err := c.answer(req.Context(), challenge, scope); err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.Body != nil {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("%s %s: unauthorized", req.Method, req.URL.Redacted())
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	c.authorize(retry, scope)
+	return c.httpc.Do(retry)
+}
+
+func (c *Client) authorize(req *http.Request, scope string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tok, ok := c.tokens[scope]; ok {
+		req.Header.Set("Authorization", "Bearer "+tok)
+		return
+	}
+	if c.basic && (c.User != "" || c.Pass != "") {
+		req.SetBasicAuth(c.User, c.Pass)
+	}
+}
+
+// answer handles a WWW-Authenticate challenge for scope.
+func (c *Client) answer(ctx context.Context, challenge, scope string) error {
+	kind, params := parseChallenge(challenge)
+	switch kind {
+	case "basic":
+		if c.User == "" && c.Pass == "" {
+			return fmt.Errorf("registry %s requires credentials", c.Host)
+		}
+		c.mu.Lock()
+		c.basic = true
+		c.mu.Unlock()
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("registry %s: unsupported auth challenge %q", c.Host, challenge)
+	}
+
+	u, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("registry %s: bad token realm %q", c.Host, params["realm"])
+	}
+	q := u.Query()
+	if s := params["service"]; s != "" {
+		q.Set("service", s)
+	}
+	// our scope may name several repositories (cross-repo mounts)
+	if scope == "" {
+		scope = params["scope"]
+	}
+	for _, s := range strings.Fields(scope) {
+		q.Add("scope", s)
+	}
+	u.RawQuery = q.Encode()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if c.User != "" || c.Pass != "" {
+		req.SetBasicAuth(c.User, c.Pass)
+	}
+	resp, err := c.httpc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("registry %s: token request: status %s", c.Host, resp.Status)
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return fmt.Errorf("registry %s: token response: %w", c.Host, err)
+	}
+	t := tok.Token
+	if t == "" {
+		t = tok.AccessToken
+	}
+	c.mu.Lock()
+	c.tokens[scope] = t
+	c.mu.Unlock()
+	return nil
+}
+
+// parseChallenge splits `Bearer realm="...",service="..."` into its scheme
+// (lower-cased) and parameters.
+func parseChallenge(h string) (string, map[string]string) {
+	kind, rest, _ := strings.Cut(strings.TrimSpace(h), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var kv string
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(rest[:eq])
+		rest = rest[eq+1:]
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				kv, rest = rest[1:], ""
+			} else {
+				kv, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			kv, rest, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(key)] = kv
+	}
+	return strings.ToLower(kind), params
+}
+
+// Repository is a Target backed by a remote repository.
+type Repository struct {
+	c    *Client
+	Name string
+}
+
+func (r *Repository) pullScope() string { return "repository:" + r.Name + ":pull" }
+func (r *Repository) pushScope() string { return "repository:" + r.Name + ":pull,push" }
+
+func (r *Repository) url(ctx context.Context, format string, a ...any) (string, error) {
+	base, err := r.c.baseURL(ctx)
+	if err != nil {
+		return "", err
+	}
+	return base + "/v2/" + r.Name + fmt.Sprintf(format, a...), nil
+}
+
+func (r *Repository) Manifest(ctx context.Context, reference string) ([]byte, string, error) {
+	u, err := r.url(ctx, "/manifests/%s", reference)
+	if err != nil {
+		return nil, "", err
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	req.Header.Set("Accept", strings.Join(manifestAccept, ", "))
+	resp, err := r.c.do(req, r.pullScope())
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, "", fmt.Errorf("GET %s/%s: %w", r.Name, reference, err)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if IsDigest(reference) && Digest(b) != reference {
+		return nil, "", fmt.Errorf("GET %s@%s: manifest digest mismatch (got %s)", r.Name, reference, Digest(b))
+	}
+	mt := resp.Header.Get("Content-Type")
+	if i := strings.Index(mt, ";"); i >= 0 {
+		mt = mt[:i]
+	}
+	if !IsManifest(mt) {
+		if m, err := ParseManifest(b, ""); err == nil {
+			mt = m.MediaType
+		}
+	}
+	return b, mt, nil
+}
+
+// ManifestDigest returns the digest of a tag without fetching the body.
+func (r *Repository) ManifestDigest(ctx context.Context, reference string) (Descriptor, error) {
+	u, err := r.url(ctx, "/manifests/%s", reference)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	req.Header.Set("Accept", strings.Join(manifestAccept, ", "))
+	resp, err := r.c.do(req, r.pullScope())
+	if err != nil {
+		return Descriptor{}, err
+	}
+	resp.Body.Close()
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return Descriptor{}, fmt.Errorf("HEAD %s/%s: %w", r.Name, reference, err)
+	}
+	d := Descriptor{
+		MediaType: resp.Header.Get("Content-Type"),
+		Digest:    resp.Header.Get("Docker-Content-Digest"),
+		Size:      resp.ContentLength,
+	}
+	if d.Digest == "" {
+		// some registries only send the digest on GET
+		b, mt, err := r.Manifest(ctx, reference)
+		if err != nil {
+			return Descriptor{}, err
+		}
+		d = Descriptor{MediaType: mt, Digest: Digest(b), Size: int64(len(b))}
+	}
+	return d, nil
+}
+
+func (r *Repository) PutManifest(ctx context.Context, reference string, body []byte, mediaType string) error {
+	u, err := r.url(ctx, "/manifests/%s", reference)
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(body))
+	req.Header.Set("Content-Type", mediaType)
+	resp, err := r.c.do(req, r.pushScope())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusCreated, http.StatusOK); err != nil {
+		return fmt.Errorf("PUT %s/%s: %w", r.Name, reference, err)
+	}
+	return nil
+}
+
+func (r *Repository) HasBlob(ctx context.Context, d Descriptor) (bool, error) {
+	u, err := r.url(ctx, "/blobs/%s", d.Digest)
+	if err != nil {
+		return false, err
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	resp, err := r.c.do(req, r.pullScope())
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("HEAD %s blob %s: %w", r.Name, d.Digest, checkResponse(resp, http.StatusOK))
+}
+
+func (r *Repository) Blob(ctx context.Context, d Descriptor) (io.ReadCloser, error) {
+	u, err := r.url(ctx, "/blobs/%s", d.Digest)
+	if err != nil {
+		return nil, err
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	resp, err := r.c.do(req, r.pullScope())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s blob %s: %w", r.Name, d.Digest, err)
+	}
+	return resp.Body, nil
+}
+
+// startUpload opens an upload session (or mounts, when from is set) and
+// returns the upload location; mounted is true when no upload is needed.
+func (r *Repository) startUpload(ctx context.Context, d Descriptor, from string) (location string, mounted bool, err error) {
+	q := ""
+	if from != "" {
+		q = "?mount=" + url.QueryEscape(d.Digest) + "&from=" + url.QueryEscape(from)
+	}
+	u, err := r.url(ctx, "/blobs/uploads/%s", q)
+	if err != nil {
+		return "", false, err
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+	scope := r.pushScope()
+	if from != "" {
+		// the token must also allow pulling the source repo
+		scope += " repository:" + from + ":pull"
+	}
+	resp, err := r.c.do(req, scope)
+	if err != nil {
+		return "", false, err
+	}
+	resp.Body.Close()
+	if err := checkResponse(resp, http.StatusCreated, http.StatusAccepted); err != nil {
+		return "", false, fmt.Errorf("POST %s upload: %w", r.Name, err)
+	}
+	if resp.StatusCode == http.StatusCreated {
+		return "", true, nil
+	}
+	loc, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", false, err
+	}
+	return loc.String(), false, nil
+}
+
+func (r *Repository) PutBlob(ctx context.Context, d Descriptor, body io.Reader) error {
+	loc, _, err := r.startUpload(ctx, d, "")
+	if err != nil {
+		return err
+	}
+	return r.finishUpload(ctx, loc, d, body)
+}
+
+// finishUpload sends the whole blob to an upload location in one PUT.
+func (r *Repository) finishUpload(ctx context.Context, location string, d Descriptor, body io.Reader) error {
+	u, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("digest", d.Digest)
+	u.RawQuery = q.Encode()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), body)
+	req.ContentLength = d.Size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := r.c.do(req, r.pushScope())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusCreated); err != nil {
+		return fmt.Errorf("PUT %s blob %s: %w", r.Name, d.Digest, err)
+	}
+	return nil
+}
+
+// Mount links d from another repository on the same registry. When the
+// registry declines, the blob is not uploaded and Mount returns false.
+func (r *Repository) Mount(ctx context.Context, d Descriptor, fromRepo string) (bool, error) {
+	loc, mounted, err := r.startUpload(ctx, d, fromRepo)
+	if err != nil || mounted {
+		return mounted, err
+	}
+	// an upload session was opened instead; abandon it
+	if req, err := http.NewRequestWithContext(ctx, http.MethodDelete, loc, nil); err == nil {
+		if resp, err := r.c.do(req, r.pushScope()); err == nil {
+			resp.Body.Close()
+		}
+	}
+	return false, nil
+}
+
+// Error is a registry error response.
+type Error struct {
+	Status int
+	Code   string
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("status %d: %s: %s", e.Status, e.Code, e.Msg)
+	}
+	return fmt.Sprintf("status %d: %s", e.Status, e.Msg)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrNotFound && e.Status == http.StatusNotFound
+}
+
+func checkResponse(resp *http.Response, ok ...int) error {
+	for _, code := range ok {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+	e := &Error{Status: resp.StatusCode, Msg: http.StatusText(resp.StatusCode)}
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(b, &body) == nil && len(body.Errors) > 0 {
+		e.Code, e.Msg = body.Errors[0].Code, body.Errors[0].Message
+	} else if s := strings.TrimSpace(string(b)); s != "" {
+		e.Msg = s
+	}
+	return e
+}
+
+// IsNotFound reports whether err means the manifest or blob doesn't exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
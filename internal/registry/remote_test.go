@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBearerAuth(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newTestRegistry(t), newTestRegistry(t)
+	dst.User, dst.Pass = "robot", "s3cret"
+	want := src.pushImage(t, "app", "v1", "amd64")
+
+	if _, err := Copy(ctx, src.Client("", "").Repository("app"), dst.Client("robot", "s3cret").Repository("team/app"),
+		"v1", "v1", CopyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if body, ok := dst.hasManifest("team/app", "v1"); !ok || Digest(body) != want.Digest {
+		t.Fatal("manifest not pushed through token auth")
+	}
+	if !slices.Contains(dst.tokenScopes(), "repository:team/app:pull,push") {
+		t.Errorf("token scopes %v, want push scope for team/app", dst.tokenScopes())
+	}
+
+	_, err := Copy(ctx, src.Client("", "").Repository("app"), dst.Client("robot", "wrong").Repository("team/app"),
+		"v1", "v2", CopyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "token request") {
+		t.Errorf("wrong password: err = %v, want token request failure", err)
+	}
+}
+
+func TestBearerAuthMountScope(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.User, reg.Pass = "robot", "s3cret"
+	reg.pushImage(t, "a/src", "v1", "amd64")
+	c := reg.Client("robot", "s3cret")
+
+	if _, err := Copy(context.Background(), c.Repository("a/src"), c.Repository("b/dst"), "v1", "v1",
+		CopyOptions{MountFrom: "a/src"}); err != nil {
+		t.Fatal(err)
+	}
+	// the mount token covers pushing to b/dst and pulling from a/src
+	if !slices.Contains(reg.tokenScopes(), "repository:a/src:pull") || reg.count("mount") != 3 {
+		t.Errorf("scopes %v, %d mounts; want a/src pull scope and 3 mounts", reg.tokenScopes(), reg.count("mount"))
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.User, reg.Pass, reg.Basic = "admin", "pw", true
+	want := reg.pushImage(t, "app", "v1", "amd64")
+
+	b, _, err := reg.Client("admin", "pw").Repository("app").Manifest(context.Background(), "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Digest(b) != want.Digest {
+		t.Errorf("got %s, want %s", Digest(b), want.Digest)
+	}
+	if _, _, err := reg.Client("", "").Repository("app").Manifest(context.Background(), "v1"); err == nil ||
+		!strings.Contains(err.Error(), "requires credentials") {
+		t.Errorf("anonymous: err = %v, want credentials required", err)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	kind, p := parseChallenge(`Bearer realm="https://h/service/token",service="harbor-registry",scope="repository:a/b:pull"`)
+	if kind != "bearer" || p["realm"] != "https://h/service/token" || p["service"] != "harbor-registry" ||
+		p["scope"] != "repository:a/b:pull" {
+		t.Errorf("got %q %v", kind, p)
+	}
+	if kind, _ := parseChallenge(`Basic realm="x"`); kind != "basic" {
+		t.Errorf("got %q, want basic", kind)
+	}
+}
+
+func TestManifestDigestMismatch(t *testing.T) {
+	reg := newTestRegistry(t)
+	d := reg.pushImage(t, "app", "v1", "amd64")
+	// serve a different body under the digest
+	reg.mu.Lock()
+	reg.manifests["app"][d.Digest] = testManifest{body: []byte(`{"schemaVersion":2}`), mediaType: MediaTypeOCIManifest}
+	reg.mu.Unlock()
+
+	if _, _, err := reg.Client("", "").Repository("app").Manifest(context.Background(), d.Digest); err == nil ||
+		!strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("err = %v, want digest mismatch", err)
+	}
+}
@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+)
+
+// Verification is the result of checking a copied manifest against its source.
+type Verification struct {
+	Source Descriptor
+	Dest   Descriptor // zero when the destination lacks the tag
+	// Blobs is how many blobs (and child manifests) were checked on the
+	// destination; Missing lists the ones it lacks.
+	Blobs   int
+	Missing []Descriptor
+}
+
+// OK reports whether the digests match and nothing is missing.
+func (v *Verification) OK() bool {
+	return v.Source.Digest == v.Dest.Digest && len(v.Missing) == 0
+}
+
+// VerifyOptions tunes Verify.
+type VerifyOptions struct {
+	Blobs     bool       // also check every blob on the destination
+	Platforms []Platform // as given to Copy
+}
+
+// Verify fetches srcRef from src and dstRef from dst and compares the digests
+// of the manifest bytes. With Blobs, it also walks the destination manifest
+// (child manifests of an index, configs and layers) and checks that each one
+// exists there. A destination without dstRef leaves Dest empty; a source
+// without srcRef is an ErrNotFound error.
+func Verify(ctx context.Context, src, dst Target, srcRef, dstRef string, opts VerifyOptions) (*Verification, error) {
+	sb, smt, err := src.Manifest(ctx, srcRef)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	if len(opts.Platforms) > 0 && IsIndex(smt) {
+		// compare with the index Copy would have written
+		if sb, _, err = FilterIndex(sb, opts.Platforms); err != nil {
+			return nil, fmt.Errorf("source: %w", err)
+		}
+	}
+	v := &Verification{Source: Descriptor{MediaType: smt, Digest: Digest(sb), Size: int64(len(sb))}}
+	db, dmt, err := dst.Manifest(ctx, dstRef)
+	if IsNotFound(err) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("destination: %w", err)
+	}
+	v.Dest = Descriptor{MediaType: dmt, Digest: Digest(db), Size: int64(len(db))}
+	if opts.Blobs {
+		if err := verifyBlobs(ctx, dst, v.Dest, db, v); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// verifyBlobs checks that everything manifest d references exists on dst.
+func verifyBlobs(ctx context.Context, dst Target, d Descriptor, body []byte, v *Verification) error {
+	m, err := ParseManifest(body, d.MediaType)
+	if err != nil {
+		return fmt.Errorf("parse manifest %s: %w", d.Digest, err)
+	}
+
+	if IsIndex(m.MediaType) {
+		for _, child := range m.Manifests {
+			v.Blobs++
+			cb, cmt, err := dst.Manifest(ctx, child.Digest)
+			if IsNotFound(err) {
+				v.Missing = append(v.Missing, child)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if child.MediaType == "" {
+				child.MediaType = cmt
+			}
+			if err := verifyBlobs(ctx, dst, child, cb, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var refs []Descriptor
+	if m.Config != nil {
+		refs = append(refs, *m.Config)
+	}
+	refs = append(refs, m.Layers...)
+	for _, b := range refs {
+		if len(b.URLs) > 0 {
+			continue // foreign layer, never copied
+		}
+		v.Blobs++
+		ok, err := dst.HasBlob(ctx, b)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			v.Missing = append(v.Missing, b)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newTestRegistry(t), newTestRegistry(t)
+	src.pushIndex(t, "app", "v1", "linux/amd64", "linux/arm64/v8")
+	s, d := src.Client("", "").Repository("app"), dst.Client("", "").Repository("app")
+
+	v, err := Verify(ctx, s, d, "v1", "v1", VerifyOptions{})
+	if err != nil || v.OK() || v.Dest.Digest != "" {
+		t.Fatalf("before copy: %+v %v; want empty destination", v, err)
+	}
+
+	if _, err := Copy(ctx, s, d, "v1", "v1", CopyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	v, err = Verify(ctx, s, d, "v1", "v1", VerifyOptions{Blobs: true})
+	if err != nil || !v.OK() || v.Blobs != 8 {
+		t.Fatalf("after copy: %+v %v; want OK with 8 checked (2 children, 6 blobs)", v, err)
+	}
+
+	body, _ := dst.hasManifest("app", "v1")
+	m, _ := ParseManifest(body, "")
+	child, _ := dst.hasManifest("app", m.Manifests[0].Digest)
+	cm, _ := ParseManifest(child, "")
+	dst.dropBlob("app", cm.Layers[0].Digest)
+	v, err = Verify(ctx, s, d, "v1", "v1", VerifyOptions{Blobs: true})
+	if err != nil || v.OK() || len(v.Missing) != 1 || v.Missing[0].Digest != cm.Layers[0].Digest {
+		t.Errorf("dropped layer: %+v %v; want it reported missing", v, err)
+	}
+
+	if _, err := Verify(ctx, s, d, "gone", "gone", VerifyOptions{}); !IsNotFound(err) {
+		t.Errorf("missing source: err = %v, want not found", err)
+	}
+}
+
+func TestVerifyPlatforms(t *testing.T) {
+	ctx := context.Background()
+	src, dst := newTestRegistry(t), newTestRegistry(t)
+	src.pushIndex(t, "app", "v1", "linux/amd64", "linux/arm64/v8")
+	s, d := src.Client("", "").Repository("app"), dst.Client("", "").Repository("app")
+	platforms := []Platform{{OS: "linux", Architecture: "amd64"}}
+
+	if _, err := Copy(ctx, s, d, "v1", "v1", CopyOptions{Platforms: platforms}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := Verify(ctx, s, d, "v1", "v1", VerifyOptions{Platforms: platforms, Blobs: true}); err != nil || !v.OK() {
+		t.Errorf("filtered copy: %+v %v; want OK", v, err)
+	}
+	if v, err := Verify(ctx, s, d, "v1", "v1", VerifyOptions{}); err != nil || v.OK() {
+		t.Errorf("without platforms: %+v %v; want a mismatch", v, err)
+	}
+}
+
+func TestFilterIndexDeterministic(t *testing.T) {
+	body := []byte(`{"schemaVersion":2,"mediaType":"` + MediaTypeOCIIndex + `","manifests":[` +
+		`{"mediaType":"` + MediaTypeOCIManifest + `","digest":"sha256:a","size":1,"platform":{"architecture":"amd64","os":"linux"}},` +
+		`{"mediaType":"` + MediaTypeOCIManifest + `","digest":"sha256:b","size":1,"platform":{"architecture":"arm64","os":"linux","variant":"v8"}}]}`)
+
+	all, kept, err := FilterIndex(body, []Platform{{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64"}})
+	if err != nil || string(all) != string(body) || len(kept) != 2 {
+		t.Errorf("keeping everything changed the index: %s %v", all, err)
+	}
+	one, _, err := FilterIndex(body, []Platform{{OS: "linux", Architecture: "arm64", Variant: "v8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _, _ := FilterIndex(body, []Platform{{OS: "linux", Architecture: "arm64", Variant: "v8"}})
+	if Digest(one) != Digest(again) {
+		t.Error("filtering twice gave different digests")
+	}
+	if _, _, err := FilterIndex(body, []Platform{{OS: "linux", Architecture: "arm64", Variant: "v7"}}); err == nil {
+		t.Error("no match did not fail")
+	}
+}
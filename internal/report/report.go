@@ -0,0 +1,201 @@
+// Package report writes machine-readable records of what a sync planned and
+// did, as JSON or as JUnit XML for CI systems.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Task outcomes besides the copy outcomes (succeeded, skipped, failed).
+const (
+	Planned  = "planned"    // dry-run: would be copied
+	UpToDate = "up-to-date" // destination already has the digest
+)
+
+// Verified is the Verify state of a task whose destination matched the source.
+const Verified = "verified"
+
+// Task is one planned or executed copy.
+type Task struct {
+	Kind        string        `json:"kind"` // copy, chartrepo, delete or create-project
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	Digest      string        `json:"digest,omitempty"`
+	Size        int64         `json:"size,omitempty"`
+	Platforms   []string      `json:"platforms,omitempty"` // empty: all
+	Duration    time.Duration `json:"-"`
+	Seconds     float64       `json:"duration_seconds"`
+	Attempts    int           `json:"attempts,omitempty"`
+	Outcome     string        `json:"outcome"`
+	Class       string        `json:"error_class,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	// Set by sync --verify and verify: "verified" or what went wrong.
+	Verify     string `json:"verify,omitempty"`
+	DestDigest string `json:"destination_digest,omitempty"`
+	Blobs      int    `json:"blobs_checked,omitempty"`
+}
+
+// Report is the record of one command run.
+type Report struct {
+	Command  string         `json:"command"`
+	From     string         `json:"from,omitempty"`
+	To       string         `json:"to,omitempty"`
+	DryRun   bool           `json:"dry_run"`
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	Summary  map[string]int `json:"summary"` // tasks per outcome
+	Tasks    []Task         `json:"tasks"`
+}
+
+// Spec is one --report value: format=path.
+type Spec struct {
+	Format string // json or junit
+	Path   string
+}
+
+// ParseSpecs parses --report values such as "json=out.json".
+func ParseSpecs(values []string) ([]Spec, error) {
+	var specs []Spec
+	for _, v := range values {
+		format, path, ok := strings.Cut(v, "=")
+		format = strings.ToLower(strings.TrimSpace(format))
+		if !ok || path == "" || (format != "json" && format != "junit") {
+			return nil, fmt.Errorf("invalid --report %q: want json=<path> or junit=<path>", v)
+		}
+		specs = append(specs, Spec{Format: format, Path: path})
+	}
+	return specs, nil
+}
+
+// Write finishes r and writes it in every requested format.
+func Write(specs []Spec, r *Report) error {
+	if r.Finished.IsZero() {
+		r.Finished = time.Now().UTC()
+	}
+	r.Summary = map[string]int{}
+	for i := range r.Tasks {
+		r.Tasks[i].Seconds = r.Tasks[i].Duration.Seconds()
+		r.Summary[r.Tasks[i].Outcome]++
+	}
+	if r.Tasks == nil {
+		r.Tasks = []Task{}
+	}
+	for _, s := range specs {
+		var b []byte
+		var err error
+		if s.Format == "junit" {
+			b, err = junit(r)
+		} else {
+			b, err = json.MarshalIndent(r, "", "  ")
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeFile(s.Path, append(b, '\n')); err != nil {
+			return fmt.Errorf("write %s report: %w", s.Format, err)
+		}
+	}
+	return nil
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      float64     `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// junit renders r as one test suite with a test case per task: failed
+// tasks and verifications fail, and everything not done (dry-run,
+// up-to-date, missing on the source) is skipped.
+func junit(r *Report) ([]byte, error) {
+	name := "harair " + r.Command
+	if r.From != "" || r.To != "" {
+		name += " " + r.From + " -> " + r.To
+	}
+	suite := junitSuite{
+		Name:      name,
+		Time:      r.Finished.Sub(r.Started).Seconds(),
+		Timestamp: r.Started.Format(time.RFC3339),
+	}
+	for _, t := range r.Tasks {
+		c := junitCase{Name: t.Destination, ClassName: t.Source, Time: t.Duration.Seconds()}
+		if t.Digest != "" {
+			c.SystemOut = "digest: " + t.Digest
+		}
+		switch {
+		case t.Verify != "" && t.Verify != Verified:
+			c.Failure = &junitMessage{Message: "verify: " + t.Verify, Type: t.Verify, Text: t.Error}
+			suite.Failures++
+		case t.Outcome == "failed":
+			msg := operation(t.Kind) + " failed"
+			if t.Class != "" {
+				msg += " (" + t.Class + ")"
+			}
+			c.Failure = &junitMessage{Message: msg, Type: t.Class, Text: t.Error}
+			suite.Failures++
+		case t.Outcome == "skipped" || t.Outcome == Planned || t.Outcome == UpToDate:
+			c.Skipped = &junitMessage{Message: t.Outcome}
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Tests = len(suite.Cases)
+	b, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// operation names what a task of kind does, for failure messages.
+func operation(kind string) string {
+	switch kind {
+	case "chartrepo":
+		return "chart upload"
+	case "delete":
+		return "delete"
+	case "create-project":
+		return "create project"
+	default:
+		return "copy"
+	}
+}
+
+func writeFile(path string, b []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, b, 0o644)
+}
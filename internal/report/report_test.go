@@ -0,0 +1,137 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sample is a sync with one task of every outcome.
+func sample() *Report {
+	started := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	return &Report{Command: "sync", From: "harbor1", To: "harbor2", Started: started, Finished: started.Add(90 * time.Second),
+		Tasks: []Task{
+			{Kind: "copy", Source: "docker://h1/p/a@sha256:a", Destination: "docker://h2/p/a:v1", Digest: "sha256:a",
+				Size: 10, Platforms: []string{"linux/amd64"}, Duration: 1500 * time.Millisecond, Attempts: 1, Outcome: "succeeded"},
+			{Kind: "copy", Source: "docker://h1/p/b@sha256:b", Destination: "docker://h2/p/b:v1", Digest: "sha256:b",
+				Duration: 2 * time.Second, Attempts: 4, Outcome: "failed", Class: "server", Error: "503 Service Unavailable"},
+			{Kind: "copy", Source: "docker://h1/p/c@sha256:c", Destination: "docker://h2/p/c:v1", Outcome: "skipped", Class: "not-found"},
+			{Kind: "copy", Destination: "docker://h2/p/d:v1", Outcome: UpToDate},
+			{Kind: "chartrepo", Destination: "harbor2/charts/app-1.0.0.tgz", Outcome: "failed", Error: "no chart repository"},
+			{Kind: "delete", Destination: "docker://h2/p/e:v0", Outcome: "failed", Error: "403 Forbidden"},
+			{Kind: "create-project", Destination: "harbor2/p", Outcome: "failed", Error: "409 Conflict"},
+			{Kind: "copy", Destination: "docker://h2/p/f:v1", Outcome: "succeeded", Verify: "mismatch", DestDigest: "sha256:x"},
+		}}
+}
+
+const wantJUnit = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="harair sync harbor1 -&gt; harbor2" tests="8" failures="5" skipped="2" time="90" timestamp="2026-05-01T12:00:00Z">
+    <testcase name="docker://h2/p/a:v1" classname="docker://h1/p/a@sha256:a" time="1.5">
+      <system-out>digest: sha256:a</system-out>
+    </testcase>
+    <testcase name="docker://h2/p/b:v1" classname="docker://h1/p/b@sha256:b" time="2">
+      <failure message="copy failed (server)" type="server">503 Service Unavailable</failure>
+      <system-out>digest: sha256:b</system-out>
+    </testcase>
+    <testcase name="docker://h2/p/c:v1" classname="docker://h1/p/c@sha256:c" time="0">
+      <skipped message="skipped"></skipped>
+    </testcase>
+    <testcase name="docker://h2/p/d:v1" classname="" time="0">
+      <skipped message="up-to-date"></skipped>
+    </testcase>
+    <testcase name="harbor2/charts/app-1.0.0.tgz" classname="" time="0">
+      <failure message="chart upload failed">no chart repository</failure>
+    </testcase>
+    <testcase name="docker://h2/p/e:v0" classname="" time="0">
+      <failure message="delete failed">403 Forbidden</failure>
+    </testcase>
+    <testcase name="harbor2/p" classname="" time="0">
+      <failure message="create project failed">409 Conflict</failure>
+    </testcase>
+    <testcase name="docker://h2/p/f:v1" classname="" time="0">
+      <failure message="verify: mismatch" type="mismatch"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	specs, err := ParseSpecs([]string{"json=" + filepath.Join(dir, "out", "r.json"), "junit=" + filepath.Join(dir, "r.xml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(specs, sample()); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := os.ReadFile(filepath.Join(dir, "r.xml"))
+	if string(b) != wantJUnit {
+		t.Errorf("junit:\n%s\nwant:\n%s", b, wantJUnit)
+	}
+
+	b, _ = os.ReadFile(filepath.Join(dir, "out", "r.json"))
+	var got map[string]any
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json: %v\n%s", err, b)
+	}
+	for k, want := range map[string]any{"command": "sync", "from": "harbor1", "to": "harbor2", "dry_run": false,
+		"started": "2026-05-01T12:00:00Z", "finished": "2026-05-01T12:01:30Z"} {
+		if got[k] != want {
+			t.Errorf("json %s = %v, want %v", k, got[k], want)
+		}
+	}
+	summary, _ := json.Marshal(got["summary"])
+	if string(summary) != `{"failed":4,"skipped":1,"succeeded":2,"up-to-date":1}` {
+		t.Errorf("json summary %s", summary)
+	}
+	tasks, _ := got["tasks"].([]any)
+	if len(tasks) != 8 {
+		t.Fatalf("json: %d tasks", len(tasks))
+	}
+	first, _ := json.Marshal(tasks[0])
+	if want := `{"attempts":1,"destination":"docker://h2/p/a:v1","digest":"sha256:a","duration_seconds":1.5,` +
+		`"kind":"copy","outcome":"succeeded","platforms":["linux/amd64"],"size":10,"source":"docker://h1/p/a@sha256:a"}`; string(first) != want {
+		t.Errorf("json task:\n%s\nwant:\n%s", first, want)
+	}
+	failed, _ := tasks[1].(map[string]any)
+	if failed["error_class"] != "server" || failed["error"] != "503 Service Unavailable" {
+		t.Errorf("json failed task %v", failed)
+	}
+	verified, _ := tasks[7].(map[string]any)
+	if verified["verify"] != "mismatch" || verified["destination_digest"] != "sha256:x" {
+		t.Errorf("json verified task %v", verified)
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "r.json")
+	if err := Write([]Spec{{Format: "json", Path: path}}, &Report{Command: "sync", DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		DryRun   bool           `json:"dry_run"`
+		Finished time.Time      `json:"finished"`
+		Summary  map[string]int `json:"summary"`
+		Tasks    []Task         `json:"tasks"`
+	}
+	b, _ := os.ReadFile(path)
+	if err := json.Unmarshal(b, &got); err != nil || !got.DryRun || got.Finished.IsZero() || got.Summary == nil || got.Tasks == nil {
+		t.Errorf("empty report %s (%v), want dry_run, finished, {} and []", b, err)
+	}
+}
+
+func TestParseSpecs(t *testing.T) {
+	specs, err := ParseSpecs([]string{"JSON=a.json", "junit=b.xml"})
+	if err != nil || len(specs) != 2 || specs[0] != (Spec{"json", "a.json"}) || specs[1] != (Spec{"junit", "b.xml"}) {
+		t.Errorf("specs %v %v", specs, err)
+	}
+	for _, v := range []string{"json", "json=", "xml=a.xml"} {
+		if _, err := ParseSpecs([]string{v}); err == nil {
+			t.Errorf("%q: no error", v)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Package retry classifies copy errors and retries the transient ones with
+// exponential backoff and jitter.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/hakantongur/harair/internal/registry"
+)
+
+// Class is the kind of failure behind an error.
+type Class string
+
+const (
+	None      Class = ""
+	Network   Class = "network"    // connection refused/reset, DNS, timeouts
+	Server    Class = "server"     // 5xx from the registry
+	RateLimit Class = "rate-limit" // 429 Too Many Requests
+	Auth      Class = "auth"       // 401/403, bad or missing credentials
+	NotFound  Class = "not-found"  // manifest or repository missing on the source
+	Quota     Class = "quota"      // Harbor project storage quota exceeded
+	Other     Class = "other"
+)
+
+// Transient reports whether errors of class c are worth retrying.
+func (c Class) Transient() bool {
+	return c == Network || c == Server || c == RateLimit
+}
+
+var (
+	// Messages embed image refs, so status codes are only matched next to
+	// "status"/"code" to keep tags like 1.502 from looking like a 502.
+	quotaRe     = regexp.MustCompile(`(?i)quota exceeded|storage quota|exceed the configured upper limit`)
+	notFoundRe  = regexp.MustCompile(`(?i)manifest unknown|name unknown|repository name not known`)
+	rateLimitRe = regexp.MustCompile(`(?i)(status|code):? 429\b|toomanyrequests|too many requests`)
+	authRe      = regexp.MustCompile(`(?i)(status|code):? 40[13]\b|unauthorized|authentication required|forbidden|denied:`)
+	serverRe    = regexp.MustCompile(`(?i)(status|code):? 5\d\d\b|internal server error|bad gateway|service unavailable|gateway time-?out`)
+	networkRe   = regexp.MustCompile(`(?i)connection refused|connection reset|broken pipe|no such host|i/o timeout|tls handshake timeout|unexpected EOF|network is unreachable|no route to host`)
+)
+
+// Classify returns the class of err. Typed errors from the native engine
+// are classified by status; skopeo errors by the text of its output.
+func Classify(err error) Class {
+	if err == nil {
+		return None
+	}
+	if errors.Is(err, context.Canceled) {
+		return Other
+	}
+	if registry.IsNotFound(err) {
+		return NotFound
+	}
+	var re *registry.Error
+	if errors.As(err, &re) {
+		switch {
+		case quotaRe.MatchString(re.Msg):
+			return Quota
+		case re.Status == 429:
+			return RateLimit
+		case re.Status == 401 || re.Status == 403:
+			return Auth
+		case re.Status >= 500:
+			return Server
+		}
+		return Other
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return Network
+	}
+
+	// Order matters: Harbor reports quota as "denied", and skopeo prefixes
+	// most errors with the request that failed.
+	msg := err.Error()
+	switch {
+	case quotaRe.MatchString(msg):
+		return Quota
+	case notFoundRe.MatchString(msg):
+		return NotFound
+	case rateLimitRe.MatchString(msg):
+		return RateLimit
+	case authRe.MatchString(msg):
+		return Auth
+	case serverRe.MatchString(msg):
+		return Server
+	case networkRe.MatchString(msg):
+		return Network
+	}
+	return Other
+}
+
+// Policy says how often and how patiently to retry.
+type Policy struct {
+	Attempts int           // total tries, including the first (<1 means 1)
+	Base     time.Duration // delay before the first retry
+	Max      time.Duration // cap on any single delay
+}
+
+// Delay returns the wait before retry n (1-based): Base*2^(n-1), capped at
+// Max, with "equal jitter" in [d/2, d] so parallel workers spread out.
+func (p Policy) Delay(n int) time.Duration {
+	d := p.Base
+	for i := 1; i < n && d < p.Max; i++ {
+		d *= 2
+	}
+	if p.Max > 0 && d > p.Max {
+		d = p.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Do calls fn until it succeeds, fails with a non-transient error, or the
+// attempts are used up. It returns the number of attempts made and the last
+// error. onRetry, if set, is called before each wait.
+func (p Policy) Do(ctx context.Context, fn func() error, onRetry func(n int, err error, wait time.Duration)) (int, error) {
+	attempts := max(p.Attempts, 1)
+	var err error
+	for n := 1; ; n++ {
+		if err = fn(); err == nil || n >= attempts || !Classify(err).Transient() {
+			return n, err
+		}
+		wait := p.Delay(n)
+		if onRetry != nil {
+			onRetry(n, err, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return n, err
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hakantongur/harair/internal/registry"
+)
+
+func TestDelayBounds(t *testing.T) {
+	p := Policy{Base: 100 * time.Millisecond, Max: time.Second}
+	for _, tc := range []struct {
+		n    int
+		base time.Duration // before jitter
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second}, // capped
+		{30, time.Second},
+	} {
+		for range 200 {
+			if d := p.Delay(tc.n); d < tc.base/2 || d > tc.base {
+				t.Fatalf("Delay(%d) = %s, want in [%s, %s]", tc.n, d, tc.base/2, tc.base)
+			}
+		}
+	}
+	if d := (Policy{}).Delay(1); d != 0 {
+		t.Errorf("zero policy: Delay = %s, want 0", d)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want Class
+	}{
+		{nil, None},
+		{context.Canceled, Other},
+
+		// skopeo output
+		{errors.New(`skopeo copy failed: exit status 1
+time="..." level=fatal msg="Error parsing image name \"docker://h/p/app:v1\": reading manifest v1 in h/p/app: manifest unknown"`), NotFound},
+		{errors.New(`initializing source docker://h/p/gone:v1: reading manifest v1 in h/p/gone: name unknown: repository name not known to registry`), NotFound},
+		{errors.New(`writing manifest: uploading manifest v1 to h/p/app: denied: adding 10.0 MiB of storage resource, which when updated to current usage of 1.0 GiB will exceed the configured upper limit of 1.0 GiB.`), Quota},
+		{errors.New(`initializing source docker://h/p/app:v1: reading manifest v1 in h/p/app: unauthorized: unauthorized to access repository`), Auth},
+		{errors.New(`trying to reuse blob sha256:abc at destination: checking whether a blob sha256:abc exists in h/p/app: authentication required`), Auth},
+		{errors.New(`reading manifest v1 in h/p/app: toomanyrequests: You have reached your pull rate limit`), RateLimit},
+		{errors.New(`writing blob: happened during read: received unexpected HTTP status: 502 Bad Gateway`), Server},
+		{errors.New(`pinging container registry h: Get "https://h/v2/": dial tcp 10.0.0.1:443: connect: connection refused`), Network},
+		{errors.New(`pinging container registry h: Get "https://h/v2/": net/http: TLS handshake timeout`), Network},
+		{errors.New(`copying image docker://h/p/app:1.502: invalid reference format`), Other},
+
+		// HTTP errors from the native engine
+		{&registry.Error{Status: 429, Msg: "slow down"}, RateLimit},
+		{&registry.Error{Status: 401, Code: "UNAUTHORIZED", Msg: "authentication required"}, Auth},
+		{&registry.Error{Status: 403, Code: "DENIED", Msg: "requested access to the resource is denied"}, Auth},
+		{&registry.Error{Status: 403, Code: "DENIED", Msg: "quota exceeded"}, Quota},
+		{&registry.Error{Status: 503, Msg: "service unavailable"}, Server},
+		{&registry.Error{Status: 400, Code: "MANIFEST_INVALID", Msg: "manifest invalid"}, Other},
+		{fmt.Errorf("copy: %w", registry.ErrNotFound), NotFound},
+		{&net.OpError{Op: "dial", Err: errors.New("refused")}, Network},
+	} {
+		if got := Classify(tc.err); got != tc.want {
+			t.Errorf("Classify(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	p := Policy{Attempts: 3, Base: time.Millisecond, Max: time.Millisecond}
+	transient := &registry.Error{Status: 503, Msg: "busy"}
+
+	var calls, retries int
+	n, err := p.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+		return nil
+	}, func(int, error, time.Duration) { retries++ })
+	if n != 3 || err != nil || retries != 2 {
+		t.Errorf("transient: %d attempts, %d retries, %v", n, retries, err)
+	}
+
+	calls = 0
+	n, err = p.Do(context.Background(), func() error { calls++; return &registry.Error{Status: 401} }, nil)
+	if n != 1 || calls != 1 || err == nil {
+		t.Errorf("auth error retried: %d attempts, %v", n, err)
+	}
+
+	n, err = p.Do(context.Background(), func() error { return transient }, nil)
+	if n != 3 || !errors.Is(err, transient) {
+		t.Errorf("exhausted: %d attempts, %v", n, err)
+	}
+}
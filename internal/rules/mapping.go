@@ -0,0 +1,170 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mapping renames images on the destination. The repo is rewritten in
+// order: RepoRegex/RepoReplace, then StripPrefix, then AddPrefix. A nil
+// Mapping keeps every name.
+//
+//	mapping:
+//	  project: mirror-uruk
+//	  strip_prefix: aed/
+//	  tag: "{{.Tag}}-airgap"
+type Mapping struct {
+	Project     string `yaml:"project"`      // destination project (empty => same)
+	RepoRegex   string `yaml:"repo_regex"`   // regexp matched against the repo
+	RepoReplace string `yaml:"repo_replace"` // its replacement; $1 etc. expand
+	StripPrefix string `yaml:"strip_prefix"` // removed from the repo when present
+	AddPrefix   string `yaml:"add_prefix"`   // put in front of the repo
+	// Tag is a Go template of the destination tag, with the source's
+	// .Project, .Repo and .Tag (empty => same tag).
+	Tag string `yaml:"tag"`
+
+	re  *regexp.Regexp
+	tag *template.Template
+}
+
+// nameData is what tag templates see.
+type nameData struct {
+	Project, Repo, Tag string
+}
+
+var (
+	projectRe = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+	repoRe    = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagRe     = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+)
+
+func (m *Mapping) UnmarshalYAML(n *yaml.Node) error {
+	type plain Mapping // without this method
+	if err := decodeStrict(n, (*plain)(m), "project", "repo_regex", "repo_replace", "strip_prefix", "add_prefix", "tag"); err != nil {
+		return err
+	}
+	if err := m.compile(); err != nil {
+		return fmt.Errorf("line %d: mapping: %w", n.Line, err)
+	}
+	return nil
+}
+
+// compile checks the mapping and prepares its regexp and template.
+func (m *Mapping) compile() error {
+	if m.Project != "" && !projectRe.MatchString(m.Project) {
+		return fmt.Errorf("invalid project %q", m.Project)
+	}
+	if m.RepoReplace != "" && m.RepoRegex == "" {
+		return fmt.Errorf("repo_replace needs repo_regex")
+	}
+	if m.RepoRegex != "" {
+		re, err := regexp.Compile(m.RepoRegex)
+		if err != nil {
+			return fmt.Errorf("repo_regex: %w", err)
+		}
+		m.re = re
+	}
+	if m.Tag != "" {
+		t, err := template.New("tag").Option("missingkey=error").Parse(m.Tag)
+		if err != nil {
+			return fmt.Errorf("tag: %w", err)
+		}
+		// catch unknown fields now rather than on the first tag
+		if err := t.Execute(&strings.Builder{}, nameData{Project: "p", Repo: "r", Tag: "t"}); err != nil {
+			return fmt.Errorf("tag: %w", err)
+		}
+		m.tag = t
+	}
+	return nil
+}
+
+// prepare compiles a mapping built in code rather than decoded.
+func (m *Mapping) prepare() error {
+	if (m.RepoRegex != "" && m.re == nil) || (m.Tag != "" && m.tag == nil) {
+		return m.compile()
+	}
+	return nil
+}
+
+// Path returns the destination project and repo of project/repo.
+func (m *Mapping) Path(project, repo string) (string, string, error) {
+	if m == nil {
+		return project, repo, nil
+	}
+	if err := m.prepare(); err != nil {
+		return "", "", err
+	}
+	dp, dr := project, repo
+	if m.Project != "" {
+		dp = m.Project
+	}
+	if m.re != nil {
+		dr = m.re.ReplaceAllString(dr, m.RepoReplace)
+	}
+	if m.StripPrefix != "" {
+		dr = strings.TrimPrefix(dr, m.StripPrefix)
+	}
+	dr = m.AddPrefix + dr
+	if !repoRe.MatchString(dr) {
+		return "", "", fmt.Errorf("mapping %s/%s gives invalid repo %q", project, repo, dr)
+	}
+	return dp, dr, nil
+}
+
+// TagOf returns the destination tag of project/repo:tag.
+func (m *Mapping) TagOf(project, repo, tag string) (string, error) {
+	if m == nil || m.Tag == "" {
+		return tag, nil
+	}
+	out, err := m.render(project, repo, tag)
+	if err != nil {
+		return "", err
+	}
+	if !tagRe.MatchString(out) {
+		return "", fmt.Errorf("mapping %s/%s:%s gives invalid tag %q", project, repo, tag, out)
+	}
+	return out, nil
+}
+
+// SourceTag returns the source tag of project/repo that TagOf maps to dst.
+// It works for templates that use .Tag once, as is (e.g. "{{.Tag}}-airgap");
+// for any other template, or a dst the template can't produce, ok is false.
+func (m *Mapping) SourceTag(project, repo, dst string) (tag string, ok bool) {
+	if m == nil || m.Tag == "" {
+		return dst, true
+	}
+	const mark = "\x00tag\x00"
+	out, err := m.render(project, repo, mark)
+	if err != nil || strings.Count(out, mark) != 1 {
+		return "", false
+	}
+	prefix, suffix, _ := strings.Cut(out, mark)
+	tag, ok = strings.CutPrefix(dst, prefix)
+	if !ok {
+		return "", false
+	}
+	if tag, ok = strings.CutSuffix(tag, suffix); !ok || tag == "" {
+		return "", false
+	}
+	// the template may branch on .Tag; only trust a round trip
+	if back, err := m.TagOf(project, repo, tag); err != nil || back != dst {
+		return "", false
+	}
+	return tag, true
+}
+
+// render applies the tag template to tag.
+func (m *Mapping) render(project, repo, tag string) (string, error) {
+	if err := m.prepare(); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := m.tag.Execute(&b, nameData{Project: project, Repo: repo, Tag: tag}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
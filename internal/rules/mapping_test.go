@@ -0,0 +1,108 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMappingNames(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		m       *Mapping
+		repo    string // source repo in project uruk
+		tag     string
+		project string // want
+		dstRepo string
+		dstTag  string
+	}{
+		{"nil", nil, "aed/x", "v1", "uruk", "aed/x", "v1"},
+		{"project", &Mapping{Project: "mirror-uruk"}, "aed/x", "v1", "mirror-uruk", "aed/x", "v1"},
+		{"regex", &Mapping{RepoRegex: `^(\w+)/(\w+)$`, RepoReplace: "$2-$1"}, "aed/x", "v1", "uruk", "x-aed", "v1"},
+		{"regex no match", &Mapping{RepoRegex: `^tools/(.*)$`, RepoReplace: "t/$1"}, "aed/x", "v1", "uruk", "aed/x", "v1"},
+		{"strip prefix", &Mapping{StripPrefix: "aed/"}, "aed/x", "v1", "uruk", "x", "v1"},
+		{"strip absent prefix", &Mapping{StripPrefix: "web/"}, "aed/x", "v1", "uruk", "aed/x", "v1"},
+		{"add prefix", &Mapping{AddPrefix: "mirror/"}, "aed/x", "v1", "uruk", "mirror/aed/x", "v1"},
+		{"in order", &Mapping{RepoRegex: `^aed/`, RepoReplace: "old/", StripPrefix: "old/", AddPrefix: "new/"}, "aed/x", "v1", "uruk", "new/x", "v1"},
+		{"tag suffix", &Mapping{Tag: "{{.Tag}}-airgap"}, "aed/x", "v1", "uruk", "aed/x", "v1-airgap"},
+		{"tag fields", &Mapping{Tag: "{{.Project}}-{{.Tag}}"}, "aed/x", "v1", "uruk", "aed/x", "uruk-v1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, r, err := tc.m.Path("uruk", tc.repo)
+			if err != nil || p != tc.project || r != tc.dstRepo {
+				t.Errorf("Path = %s/%s %v, want %s/%s", p, r, err, tc.project, tc.dstRepo)
+			}
+			tag, err := tc.m.TagOf("uruk", tc.repo, tc.tag)
+			if err != nil || tag != tc.dstTag {
+				t.Errorf("TagOf = %q %v, want %q", tag, err, tc.dstTag)
+			}
+		})
+	}
+}
+
+func TestSourceTag(t *testing.T) {
+	for _, tc := range []struct {
+		tmpl string
+		dst  string
+		want string // "" => no match
+	}{
+		{"", "v1", "v1"},
+		{"{{.Tag}}-airgap", "v1.2-airgap", "v1.2"},
+		{"air-{{.Tag}}", "air-v1", "v1"},
+		{"{{.Repo}}_{{.Tag}}", "x_v1", "v1"},
+		{"{{.Tag}}-airgap", "v1", ""},                              // not made by the template
+		{"{{.Tag}}-airgap", "-airgap", ""},                         // empty source tag
+		{"{{.Tag}}-{{.Tag}}", "v1-v1", ""},                         // .Tag twice
+		{"fixed", "fixed", ""},                                     // no .Tag at all
+		{`{{if eq .Tag "v1"}}v2{{else}}{{.Tag}}{{end}}`, "v1", ""}, // v1 never maps to v1
+		{`{{if eq .Tag "v1"}}v2{{else}}{{.Tag}}{{end}}`, "v3", "v3"},
+	} {
+		m := &Mapping{Tag: tc.tmpl}
+		got, ok := m.SourceTag("uruk", "x", tc.dst)
+		if got != tc.want || ok != (tc.want != "") {
+			t.Errorf("%q: SourceTag(%q) = %q %v, want %q", tc.tmpl, tc.dst, got, ok, tc.want)
+			continue
+		}
+		if ok {
+			if back, err := m.TagOf("uruk", "x", got); err != nil || back != tc.dst {
+				t.Errorf("%q: TagOf(SourceTag(%q)) = %q %v", tc.tmpl, tc.dst, back, err)
+			}
+		}
+	}
+}
+
+func TestMappingInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		m    *Mapping
+		want string
+	}{
+		{&Mapping{Project: "Mirror"}, "invalid project"},
+		{&Mapping{Project: "-mirror"}, "invalid project"},
+		{&Mapping{RepoReplace: "x"}, "repo_replace needs repo_regex"},
+		{&Mapping{RepoRegex: "(unclosed"}, "repo_regex"},
+		{&Mapping{Tag: "{{.Tag"}, "tag"},
+		{&Mapping{Tag: "{{.Version}}"}, "tag"},
+	} {
+		if err := tc.m.compile(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: err %v, want %q", tc.m, err, tc.want)
+		}
+	}
+
+	// names made from valid settings are checked when used
+	for _, tc := range []struct {
+		m    *Mapping
+		want string
+	}{
+		{&Mapping{AddPrefix: "Web/"}, "invalid repo"},
+		{&Mapping{StripPrefix: "aed/x"}, "invalid repo"},
+		{&Mapping{RepoRegex: ".*", RepoReplace: "a//b"}, "invalid repo"},
+	} {
+		if _, _, err := tc.m.Path("uruk", "aed/x"); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Path %+v: err %v, want %q", tc.m, err, tc.want)
+		}
+	}
+	for _, tmpl := range []string{"{{.Tag}}:x", "-{{.Tag}}", "{{.Repo}}-{{.Tag}}", "{{.Tag}}" + strings.Repeat("x", 128)} {
+		if tag, err := (&Mapping{Tag: tmpl}).TagOf("uruk", "aed/x", "v1"); err == nil {
+			t.Errorf("TagOf %q = %q, want an invalid tag error", tmpl, tag)
+		}
+	}
+}
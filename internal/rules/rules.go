@@ -1,13 +1,20 @@
 package rules
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"slices"
+	"sort"
 
 	"gopkg.in/yaml.v3"
 )
 
 type File struct {
-	Projects []Project `yaml:"projects"`
+	RuleSets map[string]RuleSet `yaml:"rule_sets"`
+	Projects []Project          `yaml:"projects"`
 }
 
 type Project struct {
@@ -15,27 +22,118 @@ type Project struct {
 	Includes []string `yaml:"includes"` // repo name globs to include (empty => include all)
 	Excludes []string `yaml:"excludes"` // repo name globs to exclude
 	Tags     []string `yaml:"tags"`     // tag globs (empty => all)
+	// Platforms of multi-arch images to copy: "all" or os/arch[/variant]
+	// entries (empty => the command's --platforms, default all).
+	Platforms []string `yaml:"platforms"`
+	Mapping   *Mapping `yaml:"mapping"` // destination names (nil => same)
 }
 
 type ImageInclude struct {
 	Type    string   `yaml:"type"` // "image"
 	From    string   `yaml:"from"`
 	Project string   `yaml:"project"`
-	Repo    string   `yaml:"repo"`
-	Tags    []string `yaml:"tags"`
+	Repo    string   `yaml:"repo"` // repo name glob (empty => all)
+	Tags    []string `yaml:"tags"` // tag globs (empty => all)
+	// Platforms and Mapping as in Project; ignored on exclude entries.
+	Platforms []string `yaml:"platforms"`
+	Mapping   *Mapping `yaml:"mapping"`
 }
 
 type HelmInclude struct {
 	Type     string   `yaml:"type"` // "helm"
 	From     string   `yaml:"from"`
 	Project  string   `yaml:"project"`
-	Name     string   `yaml:"name"`
-	Versions []string `yaml:"versions"`
+	Name     string   `yaml:"name"`     // chart name glob (empty => all)
+	Versions []string `yaml:"versions"` // version globs (empty => all)
+}
+
+// Entry is one typed item of a rule set; exactly one of Image/Helm is set.
+type Entry struct {
+	Image *ImageInclude
+	Helm  *HelmInclude
 }
 
 type RuleSet struct {
-	Include []map[string]any `yaml:"include"`
-	Exclude []map[string]any `yaml:"exclude"`
+	Include []Entry `yaml:"include"`
+	Exclude []Entry `yaml:"exclude"`
+}
+
+func (e *Entry) UnmarshalYAML(n *yaml.Node) error {
+	var probe struct {
+		Type string `yaml:"type"`
+	}
+	if err := n.Decode(&probe); err != nil {
+		return err
+	}
+	switch probe.Type {
+	case "image":
+		e.Image = &ImageInclude{}
+		return decodeStrict(n, e.Image, "type", "from", "project", "repo", "tags", "platforms", "mapping")
+	case "helm":
+		// charts are copied under their own name and version
+		if k := key(n, "mapping"); k != nil {
+			return fmt.Errorf("line %d: mapping is not supported on helm entries; charts keep their names", k.Line)
+		}
+		e.Helm = &HelmInclude{}
+		return decodeStrict(n, e.Helm, "type", "from", "project", "name", "versions")
+	case "":
+		return fmt.Errorf("line %d: rule entry is missing \"type\" (image or helm)", n.Line)
+	default:
+		return fmt.Errorf("line %d: unknown rule type %q (want image or helm)", n.Line, probe.Type)
+	}
+}
+
+// decodeStrict decodes mapping node n into out, rejecting keys not in known.
+// Custom unmarshalers don't inherit the outer decoder's KnownFields setting.
+func decodeStrict(n *yaml.Node, out any, known ...string) error {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if !slices.Contains(known, k.Value) {
+				return fmt.Errorf("line %d: field %s not allowed (want one of %v)", k.Line, k.Value, known)
+			}
+		}
+	}
+	return n.Decode(out)
+}
+
+// key returns the key node name of mapping node n, or nil.
+func key(n *yaml.Node, name string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == name {
+			return n.Content[i]
+		}
+	}
+	return nil
+}
+
+// RuleSet returns the named rule set.
+func (f *File) RuleSet(name string) (*RuleSet, error) {
+	rs, ok := f.RuleSets[name]
+	if !ok {
+		var names []string
+		for n := range f.RuleSets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("rule set %q not found (have %v)", name, names)
+	}
+	return &rs, nil
+}
+
+func (rs *RuleSet) validate(name string) error {
+	for _, e := range append(append([]Entry{}, rs.Include...), rs.Exclude...) {
+		switch {
+		case e.Image != nil && e.Image.Project == "":
+			return fmt.Errorf("rule set %q: image entry without project", name)
+		case e.Helm != nil && e.Helm.Project == "":
+			return fmt.Errorf("rule set %q: helm entry without project", name)
+		}
+	}
+	return nil
 }
 
 func Load(path string) (*File, error) {
@@ -44,8 +142,15 @@ func Load(path string) (*File, error) {
 		return nil, err
 	}
 	var f File
-	if err := yaml.Unmarshal(b, &f); err != nil {
-		return nil, err
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, rs := range f.RuleSets {
+		if err := rs.validate(name); err != nil {
+			return nil, err
+		}
 	}
 	return &f, nil
 }
@@ -0,0 +1,164 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T, content string) (*File, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoad(t *testing.T) {
+	f, err := load(t, `rule_sets:
+  core-images:
+    include:
+      - type: image
+        from: harbor1
+        project: uruk
+        repo: "aed/*"
+        tags: ["v*"]
+        platforms: ["linux/amd64"]
+        mapping:
+          project: mirror
+          tag: "{{.Tag}}-airgap"
+      - type: helm
+        project: charts
+        name: app
+        versions: ["1.*"]
+    exclude:
+      - type: image
+        project: uruk
+        tags: ["*-rc"]
+projects:
+  - name: uruk
+    includes: ["aed/*"]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := f.RuleSet("core-images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Include) != 2 || len(rs.Exclude) != 1 {
+		t.Fatalf("rule set %+v", rs)
+	}
+	img, helm := rs.Include[0].Image, rs.Include[1].Helm
+	if img == nil || rs.Include[0].Helm != nil || img.From != "harbor1" || img.Repo != "aed/*" ||
+		!slices.Equal(img.Platforms, []string{"linux/amd64"}) || img.Mapping == nil || img.Mapping.Project != "mirror" {
+		t.Errorf("image entry %+v", img)
+	}
+	if tag, err := img.Mapping.TagOf("uruk", "aed/x", "v1"); err != nil || tag != "v1-airgap" {
+		t.Errorf("decoded mapping: TagOf = %q %v", tag, err)
+	}
+	if helm == nil || rs.Include[1].Image != nil || helm.Name != "app" || !slices.Equal(helm.Versions, []string{"1.*"}) {
+		t.Errorf("helm entry %+v", helm)
+	}
+	if ex := rs.Exclude[0].Image; ex == nil || !slices.Equal(ex.Tags, []string{"*-rc"}) {
+		t.Errorf("exclude %+v", ex)
+	}
+	if len(f.Projects) != 1 || f.Projects[0].Name != "uruk" {
+		t.Errorf("projects %+v", f.Projects)
+	}
+	if _, err := f.RuleSet("other"); err == nil || !strings.Contains(err.Error(), "core-images") {
+		t.Errorf("unknown rule set: %v", err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", `rule_sets:
+  s:
+    include:
+      - type: image
+        project: uruk
+        tag: v1
+`, "field tag not allowed"},
+		{"unknown top-level key", `rulesets: {}
+`, "field rulesets not found"},
+		{"unknown mapping key", `projects:
+  - name: uruk
+    mapping:
+      repo: x
+`, "field repo not allowed"},
+		{"helm and image", `rule_sets:
+  s:
+    include:
+      - type: helm
+        project: charts
+        repo: app
+`, "field repo not allowed"},
+		{"image and helm", `rule_sets:
+  s:
+    include:
+      - type: image
+        project: uruk
+        versions: ["1.*"]
+`, "field versions not allowed"},
+		{"helm mapping", `rule_sets:
+  s:
+    include:
+      - type: helm
+        project: charts
+        mapping:
+          project: other
+`, "mapping is not supported on helm entries"},
+		{"missing type", `rule_sets:
+  s:
+    include:
+      - project: uruk
+`, `missing "type"`},
+		{"unknown type", `rule_sets:
+  s:
+    include:
+      - type: chart
+        project: uruk
+`, `unknown rule type "chart"`},
+		{"image without project", `rule_sets:
+  s:
+    include:
+      - type: image
+        repo: app
+`, "image entry without project"},
+		{"helm exclude without project", `rule_sets:
+  s:
+    exclude:
+      - type: helm
+        name: app
+`, "helm entry without project"},
+		{"invalid mapping", `projects:
+  - name: uruk
+    mapping:
+      project: Mirror
+`, "invalid project"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := load(t, tc.content); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadEmpty(t *testing.T) {
+	f, err := load(t, "")
+	if err != nil || len(f.RuleSets) != 0 || len(f.Projects) != 0 {
+		t.Errorf("empty file: %+v %v", f, err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+}
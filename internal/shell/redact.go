@@ -0,0 +1,60 @@
+package shell
+
+import (
+	"encoding/base64"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const mask = "*****"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+
+	// flags whose next argument (or "=value") is a secret
+	secretFlagRe = regexp.MustCompile(`(?i)(--(?:src-|dest-)?(?:creds|password|registry-token|token)[= ])(\S+)`)
+	// user:pass@ in URLs
+	urlUserinfoRe = regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`)
+)
+
+// RegisterSecret makes Redact mask every occurrence of s.
+func RegisterSecret(s string) {
+	if len(s) < 3 { // masking "a" or "ab" would shred unrelated output
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, x := range secrets {
+		if x == s {
+			return
+		}
+	}
+	secrets = append(secrets, s)
+	// longest first, so a secret containing another is masked whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+// RegisterCredentials registers a password together with the basic-auth
+// encoding of user:pass.
+func RegisterCredentials(user, pass string) {
+	if pass == "" {
+		return
+	}
+	RegisterSecret(pass)
+	RegisterSecret(base64.StdEncoding.EncodeToString([]byte(user + ":" + pass)))
+}
+
+// Redact masks registered secrets, credential flags and URL userinfo in s.
+func Redact(s string) string {
+	secretsMu.RLock()
+	for _, x := range secrets {
+		s = strings.ReplaceAll(s, x, mask)
+	}
+	secretsMu.RUnlock()
+	s = secretFlagRe.ReplaceAllString(s, "${1}"+mask)
+	s = urlUserinfoRe.ReplaceAllString(s, "${1}"+mask+"@")
+	return s
+}
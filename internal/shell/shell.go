@@ -2,19 +2,47 @@ package shell
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 )
 
+// Verbose makes Run print each command line (redacted) to stderr.
+var Verbose bool
+
+// Run executes name with args and returns its stdout. The error, if any,
+// includes the command line and stderr, both passed through Redact.
 func Run(name string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
+	return RunInput("", name, args...)
+}
+
+// RunContext is Run with a context that kills the command when done.
+func RunContext(ctx context.Context, name string, args ...string) (string, error) {
+	return run(ctx, "", name, args...)
+}
+
+// RunInput is Run with input fed to the command's stdin.
+func RunInput(input, name string, args ...string) (string, error) {
+	return run(context.Background(), input, name, args...)
+}
+
+func run(ctx context.Context, input, name string, args ...string) (string, error) {
+	line := Redact(name + " " + strings.Join(args, " "))
+	if Verbose {
+		fmt.Fprintln(os.Stderr, "[exec]", line)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
 	var out bytes.Buffer
 	var errb bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &errb
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("%s %s failed: %v\n%s", name, strings.Join(args, " "), err, errb.String())
+		return "", fmt.Errorf("%s failed: %s\n%s", line, Redact(err.Error()), Redact(errb.String()))
 	}
 	return out.String(), nil
 }
@@ -0,0 +1,63 @@
+package shell
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = old }()
+	fn()
+	w.Close()
+	b, _ := io.ReadAll(r)
+	return string(b)
+}
+
+func TestRunRedacts(t *testing.T) {
+	RegisterCredentials("admin", "pa55word!")
+	Verbose = true
+	defer func() { Verbose = false }()
+
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		leak   string
+		failed bool
+	}{
+		{"registered password", []string{"-c", "echo login admin:pa55word! >&2; exit 1", "sh"}, "pa55word!", true},
+		{"--creds flag", []string{"-c", "exit 0", "sh", "--creds=admin:hunter22"}, "hunter22", false},
+		{"--creds in stderr", []string{"-c", "echo bad --creds admin:hunter22 >&2; exit 3", "sh"}, "hunter22", true},
+		{"basic-auth URL", []string{"-c", "echo GET http://admin:hunter22@h/v2/ >&2; exit 1", "sh", "http://admin:hunter22@h"}, "hunter22", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			verbose := captureStderr(t, func() { _, err = Run("sh", tc.args...) })
+			if (err != nil) != tc.failed {
+				t.Fatalf("err = %v, want failed=%v", err, tc.failed)
+			}
+			if !strings.HasPrefix(verbose, "[exec] sh ") || strings.Contains(verbose, tc.leak) {
+				t.Errorf("verbose line %q", verbose)
+			}
+			if err != nil && (strings.Contains(err.Error(), tc.leak) || !strings.Contains(err.Error(), mask)) {
+				t.Errorf("error %q", err)
+			}
+		})
+	}
+}
+
+func TestRunInput(t *testing.T) {
+	out, err := RunInput("hello", "cat")
+	if err != nil || out != "hello" {
+		t.Errorf("got %q %v", out, err)
+	}
+}